@@ -5,11 +5,15 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // DiffFile represents a file in a Git diff
@@ -23,38 +27,68 @@ type DiffFile struct {
 	Status    string `json:"status"`    // A=added, M=modified, D=deleted, R=renamed, C=copied
 	Additions int    `json:"additions"` // Number of lines added
 	Deletions int    `json:"deletions"` // Number of lines deleted
+	Binary    bool   `json:"binary"`    // Binary files have no line counts or textual diff
 }
 
 // GitRawDiff returns a structured representation of the Git diff between two commits or references
 // If 'to' is empty, it will show unstaged changes (diff with working directory)
 func GitRawDiff(repoDir, from, to string) ([]DiffFile, error) {
-	// Git command to generate the diff in raw format with full hashes and rename/copy detection
-	// --find-copies-harder enables more aggressive copy detection
-	var rawCmd, numstatCmd *exec.Cmd
+	return rawDiff(repoDir, diffRefs(from, to)...)
+}
+
+// GitWorkingTreeDiff returns a structured representation of uncommitted changes
+// If staged is true, it diffs the index against HEAD; otherwise it diffs the working tree against the index.
+func GitWorkingTreeDiff(repoDir string, staged bool) ([]DiffFile, error) {
+	if staged {
+		return rawDiff(repoDir, "--cached")
+	}
+	return rawDiff(repoDir)
+}
+
+// diffRefs returns the git diff arguments for comparing from with to, or with the working directory if 'to' is empty
+func diffRefs(from, to string) []string {
 	if to == "" {
-		// If 'to' is empty, show unstaged changes
-		rawCmd = exec.Command("git", "-C", repoDir, "diff", "--raw", "--abbrev=40", "-M", "-C", "--find-copies-harder", from)
-		numstatCmd = exec.Command("git", "-C", repoDir, "diff", "--numstat", from)
-	} else {
-		// Normal diff between two refs
-		rawCmd = exec.Command("git", "-C", repoDir, "diff", "--raw", "--abbrev=40", "-M", "-C", "--find-copies-harder", from, to)
-		numstatCmd = exec.Command("git", "-C", repoDir, "diff", "--numstat", from, to)
+		return []string{from}
 	}
+	return []string{from, to}
+}
+
+// rawDiff runs git diff with diffArgs selecting what to compare and returns the structured result
+func rawDiff(repoDir string, diffArgs ...string) ([]DiffFile, error) {
+	// Git command to generate the diff in raw format with full hashes and rename/copy detection
+	// --find-copies-harder enables more aggressive copy detection
+	args := append([]string{"-C", repoDir, "diff", "--raw", "--abbrev=40", "-M", "-C", "--find-copies-harder"}, diffArgs...)
 
 	// Execute raw diff command
-	rawOut, err := rawCmd.CombinedOutput()
+	rawOut, err := exec.Command("git", args...).CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("error executing git diff --raw: %w - %s", err, string(rawOut))
 	}
 
-	// Execute numstat command
-	numstatOut, err := numstatCmd.CombinedOutput()
+	// Parse the raw diff output into structured format
+	files, err := parseRawDiff(string(rawOut))
 	if err != nil {
-		return nil, fmt.Errorf("error executing git diff --numstat: %w - %s", err, string(numstatOut))
+		return nil, err
 	}
 
-	// Parse the raw diff output into structured format
-	return parseRawDiffWithNumstat(string(rawOut), string(numstatOut))
+	// Merge in the line counts; numstat uses the same rename/copy detection, so paths line up
+	stats, err := numstat(repoDir, diffArgs...)
+	if err != nil {
+		return nil, err
+	}
+	statsByPath := make(map[string]NumstatFile, len(stats))
+	for _, stat := range stats {
+		statsByPath[stat.Path] = stat
+	}
+	for i := range files {
+		if stat, found := statsByPath[files[i].Path]; found {
+			files[i].Additions = stat.Additions
+			files[i].Deletions = stat.Deletions
+			files[i].Binary = stat.Binary
+		}
+	}
+
+	return files, nil
 }
 
 // GitShow returns the result of git show for a specific commit hash
@@ -67,53 +101,101 @@ func GitShow(repoDir, hash string) (string, error) {
 	return string(out), nil
 }
 
-// parseRawDiffWithNumstat converts git diff --raw and --numstat output into structured format
-func parseRawDiffWithNumstat(rawOutput, numstatOutput string) ([]DiffFile, error) {
-	// First parse the raw diff to get the base file information
-	files, err := parseRawDiff(rawOutput)
+// GitShowFormat returns commit metadata for a specific commit hash, formatted with a git pretty format
+// For example, format "%an <%ae>" returns the author's name and email.
+func GitShowFormat(repoDir, hash, format string) (string, error) {
+	out, err := exec.Command("git", "-C", repoDir, "show", "--no-patch", "--format="+format, hash).Output()
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("error executing git show --format: %w - %s", err, exitErrStderr(err))
 	}
+	return string(out), nil
+}
+
+// GitShowFile returns the contents of a file as of a specific commit hash
+// The path is relative to the repository root.
+func GitShowFile(repoDir, hash, path string) (string, error) {
+	out, err := exec.Command("git", "-C", repoDir, "show", hash+":"+path).Output()
+	if err != nil {
+		return "", fmt.Errorf("error executing git show %s:%s: %w - %s", hash, path, err, exitErrStderr(err))
+	}
+	return string(out), nil
+}
+
+// exitErrStderr returns the stderr captured by exec.Cmd.Output, if err carries any
+func exitErrStderr(err error) string {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return string(exitErr.Stderr)
+	}
+	return ""
+}
+
+// NumstatFile represents the line counts for a file in a Git diff
+type NumstatFile struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path"` // Original path for renames and copies
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary"` // Binary files have no line counts
+}
+
+// GitNumstat returns the number of lines added and deleted per file between two commits or references
+// If 'to' is empty, it will show unstaged changes (diff with working directory)
+func GitNumstat(repoDir, from, to string) ([]NumstatFile, error) {
+	return numstat(repoDir, diffRefs(from, to)...)
+}
+
+// numstat runs git diff --numstat with diffArgs selecting what to compare
+func numstat(repoDir string, diffArgs ...string) ([]NumstatFile, error) {
+	args := append([]string{"-C", repoDir, "diff", "--numstat", "-z", "-M", "-C", "--find-copies-harder"}, diffArgs...)
 
-	// Create a map to store numstat data by file path
-	numstatMap := make(map[string]struct{ additions, deletions int })
-
-	// Parse numstat output
-	if numstatOutput != "" {
-		scanner := bufio.NewScanner(strings.NewReader(strings.TrimSpace(numstatOutput)))
-		for scanner.Scan() {
-			line := scanner.Text()
-			// Format: additions\tdeletions\tfilename
-			// Example: 5\t3\tpath/to/file.go
-			parts := strings.Split(line, "\t")
-			if len(parts) >= 3 {
-				additions := 0
-				deletions := 0
-
-				// Handle binary files (marked with "-")
-				if parts[0] != "-" {
-					if add, err := fmt.Sscanf(parts[0], "%d", &additions); err != nil || add != 1 {
-						additions = 0
-					}
-				}
-				if parts[1] != "-" {
-					if del, err := fmt.Sscanf(parts[1], "%d", &deletions); err != nil || del != 1 {
-						deletions = 0
-					}
-				}
-
-				filePath := strings.Join(parts[2:], "\t") // Handle filenames with tabs
-				numstatMap[filePath] = struct{ additions, deletions int }{additions, deletions}
+	// Use Output rather than CombinedOutput so that warnings on stderr don't end up in the parsed output
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error executing git diff --numstat: %w - %s", err, exitErrStderr(err))
+	}
+
+	return parseNumstat(string(out))
+}
+
+// parseNumstat converts git diff --numstat -z output into structured format
+func parseNumstat(output string) ([]NumstatFile, error) {
+	var files []NumstatFile
+
+	// Format: additions\tdeletions\tpath\0
+	// For renames and copies the path is empty and both paths follow: additions\tdeletions\t\0old_path\0new_path\0
+	// Binary files have "-" for both counts.
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		if fields[i] == "" {
+			continue // Trailing terminator
+		}
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("malformed numstat entry: %q", fields[i])
+		}
+
+		file := NumstatFile{Path: parts[2]}
+		if file.Path == "" {
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("malformed numstat rename entry: %q", fields[i])
 			}
+			file.OldPath = fields[i+1]
+			file.Path = fields[i+2]
+			i += 2
 		}
-	}
 
-	// Merge numstat data into files
-	for i := range files {
-		if stats, found := numstatMap[files[i].Path]; found {
-			files[i].Additions = stats.additions
-			files[i].Deletions = stats.deletions
+		if parts[0] == "-" && parts[1] == "-" {
+			file.Binary = true
+		} else {
+			var err error
+			if file.Additions, err = strconv.Atoi(parts[0]); err != nil {
+				return nil, fmt.Errorf("malformed numstat additions for %s: %w", file.Path, err)
+			}
+			if file.Deletions, err = strconv.Atoi(parts[1]); err != nil {
+				return nil, fmt.Errorf("malformed numstat deletions for %s: %w", file.Path, err)
+			}
 		}
+		files = append(files, file)
 	}
 
 	return files, nil
@@ -205,6 +287,28 @@ func parseRawDiff(diffOutput string) ([]DiffFile, error) {
 	return files, nil
 }
 
+// ErrNoMergeBase is returned by MergeBase when the commits have no common ancestor
+var ErrNoMergeBase = errors.New("no merge base")
+
+// MergeBase returns the best common ancestor of commits a and b
+func MergeBase(repoDir, a, b string) (string, error) {
+	out, err := exec.Command("git", "-C", repoDir, "merge-base", a, b).Output()
+	if err != nil {
+		// git merge-base exits with status 1 and prints nothing when there is no common ancestor;
+		// bad refs and other failures exit with 128.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(exitErr.Stderr) == 0 {
+			return "", fmt.Errorf("%w between %s and %s", ErrNoMergeBase, a, b)
+		}
+		return "", fmt.Errorf("error executing git merge-base: %w - %s", err, exitErrStderr(err))
+	}
+	hash := strings.TrimSpace(string(out))
+	if hash == "" {
+		return "", fmt.Errorf("%w between %s and %s", ErrNoMergeBase, a, b)
+	}
+	return hash, nil
+}
+
 // GitLogEntry represents a single entry in the git log
 type GitLogEntry struct {
 	Hash    string   `json:"hash"`    // The full commit hash
@@ -220,19 +324,12 @@ func GitRecentLog(repoDir string, initialCommitHash string) ([]GitLogEntry, erro
 	}
 
 	// Find merge-base of HEAD and initial commit
-	cmdMergeBase := exec.Command("git", "-C", repoDir, "merge-base", "HEAD", initialCommitHash)
-	mergeBase, err := cmdMergeBase.CombinedOutput()
+	mergeBaseHash, err := MergeBase(repoDir, "HEAD", initialCommitHash)
 	if err != nil {
 		// If merge-base fails (which can happen in simple repos), use initialCommitHash
 		return getGitLog(repoDir, initialCommitHash)
 	}
 
-	mergeBaseHash := strings.TrimSpace(string(mergeBase))
-	if mergeBaseHash == "" {
-		// If merge-base doesn't return a valid hash, use initialCommitHash
-		return getGitLog(repoDir, initialCommitHash)
-	}
-
 	// Use the merge-base as the 'from' point
 	return getGitLog(repoDir, mergeBaseHash)
 }
@@ -299,6 +396,70 @@ func parseGitLog(logOutput string) ([]GitLogEntry, error) {
 	return entries, nil
 }
 
+// Commit describes a single commit, as returned by GitLog
+type Commit struct {
+	Hash        string    `json:"hash"`
+	ShortHash   string    `json:"short_hash"`
+	AuthorName  string    `json:"author_name"`
+	AuthorEmail string    `json:"author_email"`
+	AuthorDate  time.Time `json:"author_date"`
+	Subject     string    `json:"subject"`
+	Body        string    `json:"body"`    // The commit message after the subject, without trailing newlines
+	Parents     []string  `json:"parents"` // Full hashes of the parent commits; more than one for merges
+}
+
+// gitLogFormat is the --format for GitLog; it must have commitFields NUL-separated fields
+const gitLogFormat = "%H%x00%h%x00%an%x00%ae%x00%aI%x00%P%x00%s%x00%b"
+
+const commitFields = 8
+
+// GitLog returns the commits in revRange (anything git log accepts, e.g. "main..HEAD"), newest first
+// If revRange is empty, it lists the commits reachable from HEAD.
+func GitLog(repoDir string, revRange string) ([]Commit, error) {
+	// -z terminates each commit with NUL as well, so the output is a flat list of NUL-separated fields.
+	// NUL can't appear in commit messages, so this can't be confused by their contents.
+	args := []string{"-C", repoDir, "log", "-z", "--format=" + gitLogFormat}
+	if revRange != "" {
+		args = append(args, revRange)
+	}
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error executing git log: %w - %s", err, exitErrStderr(err))
+	}
+	return parseCommits(string(out))
+}
+
+// parseCommits parses the output of git log -z --format=gitLogFormat
+func parseCommits(output string) ([]Commit, error) {
+	var commits []Commit
+	if output == "" {
+		return commits, nil
+	}
+
+	fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	if len(fields)%commitFields != 0 {
+		return nil, fmt.Errorf("unexpected git log output: %d fields is not a multiple of %d", len(fields), commitFields)
+	}
+	for f := range slices.Chunk(fields, commitFields) {
+		date, err := time.Parse(time.RFC3339, f[4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid author date for commit %s: %w", f[0], err)
+		}
+		commits = append(commits, Commit{
+			Hash:        f[0],
+			ShortHash:   f[1],
+			AuthorName:  f[2],
+			AuthorEmail: f[3],
+			AuthorDate:  date,
+			Parents:     strings.Fields(f[5]),
+			Subject:     f[6],
+			Body:        strings.TrimRight(f[7], "\n"),
+		})
+	}
+
+	return commits, nil
+}
+
 // parseRefs extracts references from git decoration format
 func parseRefs(decoration string) []string {
 	// The decoration format from %d is: (HEAD -> main, origin/main, tag: v1.0.0)
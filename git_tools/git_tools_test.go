@@ -1,12 +1,14 @@
 package git_tools
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func setupTestRepo(t *testing.T) string {
@@ -132,6 +134,49 @@ func TestGitShow(t *testing.T) {
 	}
 }
 
+func TestGitShowFormat(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	defer os.RemoveAll(repoDir)
+
+	commitHash := createAndCommitFile(t, repoDir, "test.txt", "test content\n", true)
+
+	out, err := GitShowFormat(repoDir, commitHash, "%H%n%an <%ae>%n%s")
+	if err != nil {
+		t.Fatalf("GitShowFormat failed: %v", err)
+	}
+	want := commitHash + "\nTest User <test@example.com>\nAdd test.txt\n"
+	if out != want {
+		t.Errorf("GitShowFormat = %q, want %q", out, want)
+	}
+
+	if _, err := GitShowFormat(repoDir, "invalid", "%H"); err == nil {
+		t.Error("Expected error for invalid commit hash, got none")
+	}
+}
+
+func TestGitShowFile(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	defer os.RemoveAll(repoDir)
+
+	if err := os.Mkdir(filepath.Join(repoDir, "dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	firstHash := createAndCommitFile(t, repoDir, "dir/test.txt", "first\n", true)
+	createAndCommitFile(t, repoDir, "dir/test.txt", "second\n", true)
+
+	got, err := GitShowFile(repoDir, firstHash, "dir/test.txt")
+	if err != nil {
+		t.Fatalf("GitShowFile failed: %v", err)
+	}
+	if got != "first\n" {
+		t.Errorf("GitShowFile = %q, want %q", got, "first\n")
+	}
+
+	if _, err := GitShowFile(repoDir, firstHash, "missing.txt"); err == nil {
+		t.Error("Expected error for missing file, got none")
+	}
+}
+
 func TestParseGitLog(t *testing.T) {
 	// Test with the format from --pretty="%H%x00%s%x00%d"
 	logOutput := "abc123\x00Initial commit\x00 (HEAD -> main, origin/main)\n" +
@@ -566,3 +611,219 @@ func TestGitSaveFile(t *testing.T) {
 		t.Error("GitSaveFile should have rejected an untracked file")
 	}
 }
+
+func TestGitNumstat(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	defer os.RemoveAll(repoDir)
+
+	longContent := ""
+	for i := range 20 {
+		longContent += fmt.Sprintf("line %d of a file that will be renamed\n", i)
+	}
+	createAndCommitFile(t, repoDir, "edited.txt", "one\ntwo\nthree\n", true)
+	createAndCommitFile(t, repoDir, "old name.txt", longContent, true)
+	base := createAndCommitFile(t, repoDir, "image.bin", "\x00\x01\x02", true)
+
+	// Edit one file, rename (and slightly change) another, and change the binary file
+	if err := os.WriteFile(filepath.Join(repoDir, "edited.txt"), []byte("one\n2\nthree\nfour\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", repoDir, "mv", "old name.txt", "new name.txt").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to rename file: %v - %s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "new name.txt"), []byte(longContent+"one more line\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "image.bin"), []byte("\x00\x03"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", repoDir, "commit", "-a", "-m", "More changes").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to commit: %v - %s", err, out)
+	}
+	head := "HEAD"
+
+	stats, err := GitNumstat(repoDir, base, head)
+	if err != nil {
+		t.Fatalf("GitNumstat failed: %v", err)
+	}
+	got := make(map[string]NumstatFile)
+	for _, s := range stats {
+		got[s.Path] = s
+	}
+	want := map[string]NumstatFile{
+		"edited.txt":   {Path: "edited.txt", Additions: 2, Deletions: 1},
+		"new name.txt": {Path: "new name.txt", OldPath: "old name.txt", Additions: 1},
+		"image.bin":    {Path: "image.bin", Binary: true},
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d files, got %d: %+v", len(want), len(got), stats)
+	}
+	for path, w := range want {
+		if got[path] != w {
+			t.Errorf("%s: got %+v, want %+v", path, got[path], w)
+		}
+	}
+
+	// GitRawDiff carries the same counts and binary flag, including for the renamed file
+	diff, err := GitRawDiff(repoDir, base, head)
+	if err != nil {
+		t.Fatalf("GitRawDiff failed: %v", err)
+	}
+	for _, f := range diff {
+		w := want[f.Path]
+		if f.Additions != w.Additions || f.Deletions != w.Deletions {
+			t.Errorf("GitRawDiff %s: got +%d -%d, want +%d -%d", f.Path, f.Additions, f.Deletions, w.Additions, w.Deletions)
+		}
+		if f.Binary != w.Binary {
+			t.Errorf("GitRawDiff %s: got Binary=%v, want %v", f.Path, f.Binary, w.Binary)
+		}
+	}
+
+	// Invalid refs are reported
+	if _, err := GitNumstat(repoDir, "invalid", head); err == nil {
+		t.Error("Expected error for invalid commit hash, got none")
+	}
+}
+
+func TestMergeBase(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	defer os.RemoveAll(repoDir)
+
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", repoDir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v - %s", args, err, out)
+		}
+	}
+
+	base := createAndCommitFile(t, repoDir, "base.txt", "base\n", true)
+	git("branch", "-M", "main")
+	git("checkout", "-b", "feature")
+	feature := createAndCommitFile(t, repoDir, "feature.txt", "feature\n", true)
+	git("checkout", "main")
+	mainHash := createAndCommitFile(t, repoDir, "main.txt", "main\n", true)
+	git("checkout", "--orphan", "unrelated")
+	unrelated := createAndCommitFile(t, repoDir, "unrelated.txt", "unrelated\n", true)
+
+	got, err := MergeBase(repoDir, feature, mainHash)
+	if err != nil {
+		t.Fatalf("MergeBase failed: %v", err)
+	}
+	if got != base {
+		t.Errorf("MergeBase = %s, want %s", got, base)
+	}
+
+	_, err = MergeBase(repoDir, feature, unrelated)
+	if !errors.Is(err, ErrNoMergeBase) {
+		t.Errorf("Expected ErrNoMergeBase for unrelated histories, got %v", err)
+	}
+
+	_, err = MergeBase(repoDir, "invalid", mainHash)
+	if err == nil || errors.Is(err, ErrNoMergeBase) {
+		t.Errorf("Expected a git error for an invalid ref, got %v", err)
+	}
+}
+
+func TestGitWorkingTreeDiff(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	defer os.RemoveAll(repoDir)
+
+	createAndCommitFile(t, repoDir, "staged.txt", "one\n", true)
+	createAndCommitFile(t, repoDir, "unstaged.txt", "one\n", true)
+
+	// Stage a change to one file and leave the other modified in the working tree only
+	createAndCommitFile(t, repoDir, "staged.txt", "one\ntwo\n", false)
+	if out, err := exec.Command("git", "-C", repoDir, "add", "staged.txt").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to stage file: %v - %s", err, out)
+	}
+	createAndCommitFile(t, repoDir, "unstaged.txt", "uno\n", false)
+
+	tests := []struct {
+		staged        bool
+		wantPath      string
+		wantAdditions int
+		wantDeletions int
+	}{
+		{staged: true, wantPath: "staged.txt", wantAdditions: 1, wantDeletions: 0},
+		{staged: false, wantPath: "unstaged.txt", wantAdditions: 1, wantDeletions: 1},
+	}
+	for _, tt := range tests {
+		diff, err := GitWorkingTreeDiff(repoDir, tt.staged)
+		if err != nil {
+			t.Fatalf("GitWorkingTreeDiff(staged=%v) failed: %v", tt.staged, err)
+		}
+		if len(diff) != 1 {
+			t.Fatalf("GitWorkingTreeDiff(staged=%v): expected 1 file, got %+v", tt.staged, diff)
+		}
+		f := diff[0]
+		if f.Path != tt.wantPath || f.Status != "M" || f.Additions != tt.wantAdditions || f.Deletions != tt.wantDeletions {
+			t.Errorf("GitWorkingTreeDiff(staged=%v) = %+v, want M %s +%d -%d", tt.staged, f, tt.wantPath, tt.wantAdditions, tt.wantDeletions)
+		}
+	}
+}
+
+func TestGitLog(t *testing.T) {
+	repoDir := setupTestRepo(t)
+	defer os.RemoveAll(repoDir)
+
+	first := createAndCommitFile(t, repoDir, "a.txt", "a\n", true)
+	if err := os.WriteFile(filepath.Join(repoDir, "b.txt"), []byte("b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("git", "-C", repoDir, "commit", "-q", "--author", "Other Author <other@example.com>",
+		"-m", "Add b.txt", "-m", "A body\nwith two lines.\n\nAnd a trailing paragraph.")
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE=2025-01-02T03:04:05+01:00")
+	if out, err := exec.Command("git", "-C", repoDir, "add", "b.txt").CombinedOutput(); err != nil {
+		t.Fatalf("Failed to add file: %v - %s", err, out)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to commit: %v - %s", err, out)
+	}
+
+	commits, err := GitLog(repoDir, "")
+	if err != nil {
+		t.Fatalf("GitLog failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d: %+v", len(commits), commits)
+	}
+
+	// Newest first
+	c := commits[0]
+	if c.AuthorName != "Other Author" || c.AuthorEmail != "other@example.com" {
+		t.Errorf("Unexpected author: %q <%q>", c.AuthorName, c.AuthorEmail)
+	}
+	if want := time.Date(2025, 1, 2, 2, 4, 5, 0, time.UTC); !c.AuthorDate.Equal(want) {
+		t.Errorf("AuthorDate = %v, want %v", c.AuthorDate, want)
+	}
+	if c.Subject != "Add b.txt" {
+		t.Errorf("Subject = %q, want %q", c.Subject, "Add b.txt")
+	}
+	if want := "A body\nwith two lines.\n\nAnd a trailing paragraph."; c.Body != want {
+		t.Errorf("Body = %q, want %q", c.Body, want)
+	}
+	if len(c.Parents) != 1 || c.Parents[0] != first {
+		t.Errorf("Parents = %v, want [%s]", c.Parents, first)
+	}
+	if !strings.HasPrefix(c.Hash, c.ShortHash) || len(c.Hash) != 40 {
+		t.Errorf("Unexpected hashes: %q, %q", c.Hash, c.ShortHash)
+	}
+
+	root := commits[1]
+	if root.Hash != first || root.Subject != "Add a.txt" || root.Body != "" || len(root.Parents) != 0 {
+		t.Errorf("Unexpected root commit: %+v", root)
+	}
+
+	// Ranges are passed through to git log
+	commits, err = GitLog(repoDir, first+"..HEAD")
+	if err != nil {
+		t.Fatalf("GitLog with range failed: %v", err)
+	}
+	if len(commits) != 1 || commits[0].Subject != "Add b.txt" {
+		t.Errorf("Expected only the second commit in range, got %+v", commits)
+	}
+
+	if _, err := GitLog(repoDir, "invalid..HEAD"); err == nil {
+		t.Error("Expected error for invalid range, got none")
+	}
+}
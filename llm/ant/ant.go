@@ -11,6 +11,7 @@ import (
 	"log/slog"
 	"math/rand/v2"
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -22,8 +23,9 @@ const (
 	DefaultModel = Claude4Sonnet
 	// See https://docs.anthropic.com/en/docs/about-claude/models/all-models for
 	// current maximums. There's currently a flag to enable 128k output (output-128k-2025-02-19)
-	DefaultMaxTokens = 8192
-	DefaultURL       = "https://api.anthropic.com/v1/messages"
+	DefaultMaxTokens   = 8192
+	DefaultMaxAttempts = 10
+	DefaultURL         = "https://api.anthropic.com/v1/messages"
 )
 
 const (
@@ -54,6 +56,48 @@ func (s *Service) TokenContextWindow() int {
 	}
 }
 
+// Pricing holds a model's prices in USD per million tokens.
+type Pricing struct {
+	Input      float64
+	Output     float64
+	CacheRead  float64
+	CacheWrite float64
+}
+
+// See https://docs.anthropic.com/en/docs/about-claude/pricing
+var modelPricing = map[string]Pricing{
+	Claude35Sonnet: {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	Claude37Sonnet: {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	Claude4Sonnet:  {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	Claude35Haiku:  {Input: 0.80, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+	Claude4Opus:    {Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+}
+
+// DefaultPricing is used by CostFor for models missing from the pricing table.
+var DefaultPricing = modelPricing[DefaultModel]
+
+// CostFor estimates the cost in USD of usage billed at model's rates.
+func CostFor(model string, u llm.Usage) float64 {
+	p, ok := modelPricing[model]
+	if !ok {
+		p = DefaultPricing
+	}
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheReadInputTokens)*p.CacheRead +
+		float64(u.CacheCreationInputTokens)*p.CacheWrite) / 1e6
+}
+
+// costUSD returns the cost of a response.
+// skaband reports the billed cost in a header; without it (e.g. talking to Anthropic directly),
+// the cost is estimated from the token counts.
+func costUSD(h http.Header, model string, u usage) float64 {
+	if h.Get("Skaband-Cost-Microcents") != "" {
+		return llm.CostUSDFromResponse(h)
+	}
+	return CostFor(model, toLLMUsage(u))
+}
+
 // Service provides Claude completions.
 // Fields should not be altered concurrently with calling any method on Service.
 type Service struct {
@@ -62,6 +106,10 @@ type Service struct {
 	APIKey    string       // must be non-empty
 	Model     string       // defaults to DefaultModel if empty
 	MaxTokens int          // defaults to DefaultMaxTokens if zero
+	// MaxAttempts bounds how many times a request is sent when it fails with
+	// a retryable error (rate limits, overload, server errors).
+	// Values of zero or less mean DefaultMaxAttempts.
+	MaxAttempts int
 }
 
 var _ llm.Service = (*Service)(nil)
@@ -124,6 +172,29 @@ type content struct {
 	EndTime   *time.Time `json:"-"`
 
 	CacheControl json.RawMessage `json:"cache_control,omitempty"`
+
+	// raw holds the original JSON of content whose type we don't know,
+	// so that it can be sent back to Claude unchanged.
+	raw json.RawMessage
+}
+
+func (c *content) UnmarshalJSON(data []byte) error {
+	type plain content // avoid recursing into UnmarshalJSON
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+	if _, ok := toLLMContentType[c.Type]; !ok {
+		c.raw = bytes.Clone(data)
+	}
+	return nil
+}
+
+func (c content) MarshalJSON() ([]byte, error) {
+	if c.raw != nil {
+		return c.raw, nil
+	}
+	type plain content // avoid recursing into MarshalJSON
+	return json.Marshal(plain(c))
 }
 
 // message represents a message in the conversation.
@@ -199,9 +270,9 @@ type request struct {
 	Tools         []*tool         `json:"tools,omitempty"`
 	Stream        bool            `json:"stream,omitempty"`
 	System        []systemContent `json:"system,omitempty"`
-	Temperature   float64         `json:"temperature,omitempty"`
+	Temperature   *float64        `json:"temperature,omitempty"`
 	TopK          int             `json:"top_k,omitempty"`
-	TopP          float64         `json:"top_p,omitempty"`
+	TopP          *float64        `json:"top_p,omitempty"`
 	StopSequences []string        `json:"stop_sequences,omitempty"`
 
 	TokenEfficientToolUse bool `json:"-"` // DO NOT USE, broken on Anthropic's side as of 2025-02-28
@@ -241,6 +312,7 @@ var (
 		llm.ContentTypeRedactedThinking: "redacted_thinking",
 		llm.ContentTypeToolUse:          "tool_use",
 		llm.ContentTypeToolResult:       "tool_result",
+		llm.ContentTypeDocument:         "document",
 	}
 	toLLMContentType = inverted(fromLLMContentType)
 
@@ -268,6 +340,9 @@ func fromLLMCache(c bool) json.RawMessage {
 }
 
 func fromLLMContent(c llm.Content) content {
+	if c.Type == llm.ContentTypeUnknown {
+		return content{raw: c.Raw}
+	}
 	var toolResult []content
 	if len(c.ToolResult) > 0 {
 		toolResult = make([]content, len(c.ToolResult))
@@ -302,12 +377,28 @@ func fromLLMContent(c llm.Content) content {
 	}
 	// Anthropic API complains if Text is specified when it shouldn't be
 	// or not specified when it's the empty string.
-	if c.Type != llm.ContentTypeToolResult && c.Type != llm.ContentTypeToolUse {
+	switch c.Type {
+	case llm.ContentTypeToolResult, llm.ContentTypeToolUse:
+	case llm.ContentTypeDocument:
+		// Documents carry their data in a source block, like images.
+		d.Source = base64Source(c.MediaType, c.Data)
+		d.MediaType = ""
+		d.Data = ""
+	default:
 		d.Text = &c.Text
 	}
 	return d
 }
 
+// base64Source returns a base64 source block, as used by image and document content.
+func base64Source(mediaType, data string) json.RawMessage {
+	src, err := json.Marshal(map[string]string{"type": "base64", "media_type": mediaType, "data": data})
+	if err != nil {
+		panic(err) // marshaling strings can't fail
+	}
+	return src
+}
+
 func fromLLMToolUse(tu *llm.ToolUse) *toolUse {
 	if tu == nil {
 		return nil
@@ -361,6 +452,10 @@ func (s *Service) fromLLMRequest(r *llm.Request) *request {
 		ToolChoice: fromLLMToolChoice(r.ToolChoice),
 		Tools:      mapped(r.Tools, fromLLMTool),
 		System:     mapped(r.System, fromLLMSystem),
+
+		Temperature:   r.Temperature,
+		TopP:          r.TopP,
+		StopSequences: r.StopSequences,
 	}
 }
 
@@ -400,6 +495,10 @@ func toLLMContent(c content) llm.Content {
 	if c.Text != nil {
 		ret.Text = *c.Text
 	}
+	if c.raw != nil {
+		ret.Type = llm.ContentTypeUnknown
+		ret.Raw = c.raw
+	}
 	return ret
 }
 
@@ -416,6 +515,50 @@ func toLLMResponse(r *response) *llm.Response {
 	}
 }
 
+// retryBackoff is the wait before each retry, indexed by attempt and clamped to the last entry.
+var retryBackoff = []time.Duration{15 * time.Second, 30 * time.Second, time.Minute}
+
+// maxRetryAfter caps how long we'll honor a server's Retry-After.
+const maxRetryAfter = 5 * time.Minute
+
+// parseRetryAfter parses a Retry-After header, which is either delay-seconds or an HTTP date.
+// It reports false if the header is missing or malformed.
+func parseRetryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = max(time.Until(t), 0)
+	} else {
+		return 0, false
+	}
+	return min(d, maxRetryAfter), true
+}
+
+// newHTTPRequest returns the HTTP request that sends payload, the encoding of request, to the messages API.
+// features are anthropic-beta features to enable in addition to those request calls for.
+func (s *Service) newHTTPRequest(ctx context.Context, request *request, payload []byte, features ...string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", cmp.Or(s.URL, DefaultURL), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", s.APIKey)
+	req.Header.Set("Anthropic-Version", "2023-06-01")
+
+	if request.TokenEfficientToolUse {
+		features = append([]string{"token-efficient-tool-use-2025-02-19"}, features...)
+	}
+	if len(features) > 0 {
+		req.Header.Set("anthropic-beta", strings.Join(features, ","))
+	}
+	return req, nil
+}
+
 // Do sends a request to Anthropic.
 func (s *Service) Do(ctx context.Context, ir *llm.Request) (*llm.Response, error) {
 	request := s.fromLLMRequest(ir)
@@ -436,46 +579,47 @@ func (s *Service) Do(ctx context.Context, ir *llm.Request) (*llm.Response, error
 		fmt.Printf("claude request payload:\n%s\n", payload)
 	}
 
-	backoff := []time.Duration{15 * time.Second, 30 * time.Second, time.Minute}
 	largerMaxTokens := false
 	var partialUsage usage
 
-	url := cmp.Or(s.URL, DefaultURL)
 	httpc := cmp.Or(s.HTTPC, http.DefaultClient)
+	maxAttempts := s.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
 
 	// retry loop
-	var errs error // accumulated errors across all attempts
+	var errs error               // accumulated errors across all attempts
+	var retryAfter time.Duration // server-requested delay before the next attempt, if any
+	hasRetryAfter := false
 	for attempts := 0; ; attempts++ {
-		if attempts > 10 {
+		if attempts >= maxAttempts {
 			return nil, fmt.Errorf("anthropic request failed after %d attempts: %w", attempts, errs)
 		}
 		if attempts > 0 {
-			sleep := backoff[min(attempts, len(backoff)-1)] + time.Duration(rand.Int64N(int64(time.Second)))
+			sleep := retryBackoff[min(attempts, len(retryBackoff)-1)] + time.Duration(rand.Int64N(int64(time.Second)))
+			if hasRetryAfter {
+				sleep = retryAfter
+			}
 			slog.WarnContext(ctx, "anthropic request sleep before retry", "sleep", sleep, "attempts", attempts)
-			time.Sleep(sleep)
+			select {
+			case <-time.After(sleep):
+			case <-ctx.Done():
+				return nil, errors.Join(errs, ctx.Err())
+			}
 		}
+		retryAfter, hasRetryAfter = 0, false
 		if dumpText {
 			fmt.Printf("RAW REQUEST:\n%s\n\n", payload)
 		}
-		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
-		if err != nil {
-			return nil, errors.Join(errs, err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("X-API-Key", s.APIKey)
-		req.Header.Set("Anthropic-Version", "2023-06-01")
-
 		var features []string
-		if request.TokenEfficientToolUse {
-			features = append(features, "token-efficient-tool-use-2025-02-19")
-		}
 		if largerMaxTokens {
 			features = append(features, "output-128k-2025-02-19")
 			request.MaxTokens = 128 * 1024
 		}
-		if len(features) > 0 {
-			req.Header.Set("anthropic-beta", strings.Join(features, ","))
+		req, err := s.newHTTPRequest(ctx, request, payload, features...)
+		if err != nil {
+			return nil, errors.Join(errs, err)
 		}
 
 		resp, err := httpc.Do(req)
@@ -508,27 +652,29 @@ func (s *Service) Do(ctx context.Context, ir *llm.Request) (*llm.Response, error
 				slog.InfoContext(ctx, "anthropic_retrying_with_larger_tokens", "message", "Retrying Anthropic API call with larger max tokens size")
 				// Retry with more output tokens.
 				largerMaxTokens = true
-				response.Usage.CostUSD = llm.CostUSDFromResponse(resp.Header)
+				response.Usage.CostUSD = costUSD(resp.Header, cmp.Or(response.Model, request.Model), response.Usage)
 				partialUsage = response.Usage
 				continue
 			}
 
 			// Calculate and set the cost_usd field
+			response.Usage.CostUSD = costUSD(resp.Header, cmp.Or(response.Model, request.Model), response.Usage)
 			if largerMaxTokens {
 				response.Usage.Add(partialUsage)
 			}
-			response.Usage.CostUSD = llm.CostUSDFromResponse(resp.Header)
 
 			return toLLMResponse(&response), nil
 		case resp.StatusCode >= 500 && resp.StatusCode < 600:
-			// server error, retry
+			// server error (including 529 overloaded), retry
 			slog.WarnContext(ctx, "anthropic_request_failed", "response", string(buf), "status_code", resp.StatusCode)
 			errs = errors.Join(errs, fmt.Errorf("status %v: %s", resp.Status, buf))
+			retryAfter, hasRetryAfter = parseRetryAfter(resp.Header)
 			continue
 		case resp.StatusCode == 429:
 			// rate limited, retry
 			slog.WarnContext(ctx, "anthropic_request_rate_limited", "response", string(buf))
 			errs = errors.Join(errs, fmt.Errorf("status %v: %s", resp.Status, buf))
+			retryAfter, hasRetryAfter = parseRetryAfter(resp.Header)
 			continue
 		case resp.StatusCode >= 400 && resp.StatusCode < 500:
 			// some other 400, probably unrecoverable
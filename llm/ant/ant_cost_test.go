@@ -0,0 +1,68 @@
+package ant
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sketch.dev/llm"
+)
+
+func TestCostFor(t *testing.T) {
+	u := llm.Usage{
+		InputTokens:              1_000_000,
+		OutputTokens:             1_000_000,
+		CacheReadInputTokens:     1_000_000,
+		CacheCreationInputTokens: 1_000_000,
+	}
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{model: Claude4Sonnet, want: 3 + 15 + 0.30 + 3.75},
+		{model: Claude4Opus, want: 15 + 75 + 1.50 + 18.75},
+		{model: Claude35Haiku, want: 0.80 + 4 + 0.08 + 1},
+		{model: "claude-unknown", want: 3 + 15 + 0.30 + 3.75}, // DefaultPricing
+	}
+	for _, tt := range tests {
+		if got := CostFor(tt.model, u); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("CostFor(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestResponseCost(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   float64
+	}{
+		{name: "skaband header", header: "250000", want: 0.0025},
+		{name: "estimated", want: CostFor(Claude4Sonnet, llm.Usage{InputTokens: 1, OutputTokens: 1})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("Skaband-Cost-Microcents", tt.header)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(okResponse))
+			}))
+			defer srv.Close()
+
+			svc := &Service{URL: srv.URL, APIKey: "test"}
+			resp, err := svc.Do(context.Background(), &llm.Request{
+				Messages: []llm.Message{llm.UserStringMessage("hello")},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(resp.Usage.CostUSD-tt.want) > 1e-12 {
+				t.Errorf("CostUSD = %v, want %v", resp.Usage.CostUSD, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package ant
+
+import (
+	"encoding/json"
+	"testing"
+
+	"sketch.dev/llm"
+)
+
+func TestAnthropicDocument(t *testing.T) {
+	doc := llm.Content{
+		Type:      llm.ContentTypeDocument,
+		MediaType: "application/pdf",
+		Data:      "JVBERi0xLjQK",
+	}
+	want := `{"type":"document","source":{"data":"JVBERi0xLjQK","media_type":"application/pdf","type":"base64"}}`
+
+	got, err := json.Marshal(fromLLMContent(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("document content:\n got: %s\nwant: %s", got, want)
+	}
+
+	// Documents may also be returned from tools.
+	toolResult := fromLLMContent(llm.Content{
+		Type:       llm.ContentTypeToolResult,
+		ToolUseID:  "toolu_01",
+		ToolResult: []llm.Content{doc},
+	})
+	if len(toolResult.ToolResult) != 1 {
+		t.Fatalf("expected 1 tool result content, got %d", len(toolResult.ToolResult))
+	}
+	got, err = json.Marshal(toolResult.ToolResult[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("document in tool result:\n got: %s\nwant: %s", got, want)
+	}
+}
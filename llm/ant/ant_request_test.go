@@ -0,0 +1,113 @@
+package ant
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"sketch.dev/llm"
+)
+
+func TestSamplingParameters(t *testing.T) {
+	zero, half := 0.0, 0.5
+	tests := []struct {
+		name    string
+		req     llm.Request
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "defaults",
+			req:     llm.Request{},
+			notWant: []string{`"temperature"`, `"top_p"`, `"stop_sequences"`},
+		},
+		{
+			name: "zero temperature is sent",
+			req:  llm.Request{Temperature: &zero},
+			want: []string{`"temperature":0`},
+		},
+		{
+			name: "all set",
+			req:  llm.Request{Temperature: &half, TopP: &half, StopSequences: []string{"END"}},
+			want: []string{`"temperature":0.5`, `"top_p":0.5`, `"stop_sequences":["END"]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal((&Service{}).fromLLMRequest(&tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(b), w) {
+					t.Errorf("request %s does not contain %s", b, w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(string(b), w) {
+					t.Errorf("request %s should not contain %s", b, w)
+				}
+			}
+		})
+	}
+}
+
+func TestToolChoice(t *testing.T) {
+	tests := []struct {
+		name   string
+		choice *llm.ToolChoice
+		want   string
+	}{
+		{name: "unset", choice: nil, want: ""},
+		{name: "auto", choice: &llm.ToolChoice{Type: llm.ToolChoiceTypeAuto}, want: `{"type":"auto"}`},
+		{name: "any", choice: &llm.ToolChoice{Type: llm.ToolChoiceTypeAny}, want: `{"type":"any"}`},
+		{name: "none", choice: &llm.ToolChoice{Type: llm.ToolChoiceTypeNone}, want: `{"type":"none"}`},
+		{name: "forced tool", choice: &llm.ToolChoice{Type: llm.ToolChoiceTypeTool, Name: "bash"}, want: `{"type":"tool","name":"bash"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal((&Service{}).fromLLMRequest(&llm.Request{ToolChoice: tt.choice}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got struct {
+				ToolChoice json.RawMessage `json:"tool_choice"`
+			}
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			if string(got.ToolChoice) != tt.want {
+				t.Errorf("tool_choice = %s, want %s", got.ToolChoice, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHTTPRequestBetaFeatures(t *testing.T) {
+	svc := &Service{APIKey: "test"}
+	tests := []struct {
+		name     string
+		request  request
+		features []string
+		want     string
+	}{
+		{name: "none", want: ""},
+		{name: "from request", request: request{TokenEfficientToolUse: true}, want: "token-efficient-tool-use-2025-02-19"},
+		{name: "both", request: request{TokenEfficientToolUse: true}, features: []string{"output-128k-2025-02-19"}, want: "token-efficient-tool-use-2025-02-19,output-128k-2025-02-19"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := svc.newHTTPRequest(context.Background(), &tt.request, nil, tt.features...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get("anthropic-beta"); got != tt.want {
+				t.Errorf("anthropic-beta = %q, want %q", got, tt.want)
+			}
+			if got := req.Header.Get("X-API-Key"); got != "test" {
+				t.Errorf("X-API-Key = %q, want test", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package ant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sketch.dev/llm"
+)
+
+const okResponse = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514",` +
+	`"content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		failStatus int
+		retryAfter string
+		wantCalls  int32
+		wantErr    bool
+	}{
+		{name: "overloaded once", failStatus: 529, retryAfter: "0", wantCalls: 2},
+		{name: "rate limited once", failStatus: http.StatusTooManyRequests, retryAfter: "0", wantCalls: 2},
+		{name: "rate limited without retry-after", failStatus: http.StatusTooManyRequests, wantCalls: 2},
+		{name: "bad request", failStatus: http.StatusBadRequest, wantCalls: 1, wantErr: true},
+		{name: "unauthorized", failStatus: http.StatusUnauthorized, wantCalls: 1, wantErr: true},
+	}
+
+	oldBackoff := retryBackoff
+	retryBackoff = []time.Duration{0}
+	t.Cleanup(func() { retryBackoff = oldBackoff })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					http.Error(w, `{"type":"error"}`, tt.failStatus)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(okResponse))
+			}))
+			defer srv.Close()
+
+			svc := &Service{URL: srv.URL, APIKey: "test"}
+			resp, err := svc.Do(context.Background(), &llm.Request{
+				Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: []llm.Content{{Type: llm.ContentTypeText, Text: "hello"}}}},
+			})
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && resp.Content[0].Text != "hi" {
+				t.Errorf("got response text %q, want %q", resp.Content[0].Text, "hi")
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("server got %d calls, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, `{"type":"error"}`, 529)
+	}))
+	defer srv.Close()
+
+	svc := &Service{URL: srv.URL, APIKey: "test", MaxAttempts: 3}
+	_, err := svc.Do(context.Background(), &llm.Request{
+		Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: []llm.Content{{Type: llm.ContentTypeText, Text: "hello"}}}},
+	})
+	if err == nil {
+		t.Fatal("expected error after exhausting attempts")
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("server got %d calls, want 3", got)
+	}
+}
+
+func TestNegativeMaxAttempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(okResponse))
+	}))
+	defer srv.Close()
+
+	svc := &Service{URL: srv.URL, APIKey: "test", MaxAttempts: -1}
+	if _, err := svc.Do(context.Background(), &llm.Request{
+		Messages: []llm.Message{llm.UserStringMessage("hello")},
+	}); err != nil {
+		t.Fatalf("negative MaxAttempts should fall back to the default: %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "0", want: 0, wantOK: true},
+		{value: "7", want: 7 * time.Second, wantOK: true},
+		{value: "86400", want: maxRetryAfter, wantOK: true},
+		{value: "-1", wantOK: false},
+		{value: "soon", wantOK: false},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, wantOK: true}, // in the past
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		if tt.value != "" {
+			h.Set("Retry-After", tt.value)
+		}
+		got, ok := parseRetryAfter(h)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
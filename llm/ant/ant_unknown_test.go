@@ -0,0 +1,39 @@
+package ant
+
+import (
+	"encoding/json"
+	"testing"
+
+	"sketch.dev/llm"
+)
+
+func TestUnknownContentRoundTrip(t *testing.T) {
+	// server_tool_use isn't modeled by llm; it must survive being read from a response and sent back.
+	block := `{"type":"server_tool_use","id":"srvtoolu_01","name":"web_search","input":{"query":"golang"}}`
+	body := `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514",` +
+		`"content":[{"type":"text","text":"searching"},` + block + `],"stop_reason":"end_turn","usage":{}}`
+
+	var r response
+	if err := json.Unmarshal([]byte(body), &r); err != nil {
+		t.Fatal(err)
+	}
+	resp := toLLMResponse(&r)
+	if len(resp.Content) != 2 {
+		t.Fatalf("expected 2 contents, got %d", len(resp.Content))
+	}
+	if resp.Content[0].Type != llm.ContentTypeText || resp.Content[0].Text != "searching" {
+		t.Errorf("text content = %+v", resp.Content[0])
+	}
+	unknown := resp.Content[1]
+	if unknown.Type != llm.ContentTypeUnknown {
+		t.Fatalf("expected ContentTypeUnknown, got %v", unknown.Type)
+	}
+
+	got, err := json.Marshal(fromLLMContent(unknown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != block {
+		t.Errorf("unknown content was not re-sent verbatim:\n got: %s\nwant: %s", got, block)
+	}
+}
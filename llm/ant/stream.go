@@ -0,0 +1,199 @@
+package ant
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"sketch.dev/llm"
+)
+
+// streamEvent is a server-sent event from a streaming messages request.
+// See https://docs.anthropic.com/en/docs/build-with-claude/streaming
+type streamEvent struct {
+	Type         string   `json:"type"`
+	Message      response `json:"message"`       // message_start
+	Index        int      `json:"index"`         // content_block_*
+	ContentBlock content  `json:"content_block"` // content_block_start
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		PartialJSON string `json:"partial_json"`
+
+		// message_delta
+		StopReason   string  `json:"stop_reason"`
+		StopSequence *string `json:"stop_sequence"`
+	} `json:"delta"`
+	Usage *usage          `json:"usage"` // message_delta
+	Error json.RawMessage `json:"error"` // error
+}
+
+// DoStream sends a request to Anthropic and streams the response back as it is generated.
+// Unlike Do, it does not retry: failures before streaming starts are returned as an error,
+// and failures after that end the stream with an event whose Err is set.
+// The channel is closed after the final event.
+// Callers must read from the channel until it is closed or cancel ctx;
+// otherwise the goroutine producing events blocks forever.
+func (s *Service) DoStream(ctx context.Context, ir *llm.Request) (<-chan llm.StreamEvent, error) {
+	request := s.fromLLMRequest(ir)
+	request.Stream = true
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.newHTTPRequest(ctx, request, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cmp.Or(s.HTTPC, http.DefaultClient).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		buf, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("status %v: %s", resp.Status, buf)
+	}
+
+	events := make(chan llm.StreamEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		final := llm.StreamEvent{}
+		r, err := readStream(ctx, resp.Body, events)
+		if err != nil {
+			final.Err = err
+		} else {
+			r.Usage.CostUSD = costUSD(resp.Header, cmp.Or(r.Model, request.Model), r.Usage)
+			final.Response = toLLMResponse(r)
+		}
+		select {
+		case events <- final:
+		case <-ctx.Done():
+		}
+	}()
+	return events, nil
+}
+
+// readStream reads server-sent events from body, sending deltas to events as they arrive,
+// and returns the response they add up to.
+func readStream(ctx context.Context, body io.Reader, events chan<- llm.StreamEvent) (*response, error) {
+	send := func(ev llm.StreamEvent) error {
+		select {
+		case events <- ev:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var r response
+	toolInputs := make(map[int]*strings.Builder)
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, 16<<20) // tool inputs can make for long lines
+	for scanner.Scan() {
+		// Each event is an "event: <type>" line followed by a "data: <json>" line.
+		// The data repeats the type, so the event lines can be ignored.
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var ev streamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return nil, fmt.Errorf("malformed stream event %q: %w", data, err)
+		}
+
+		switch ev.Type {
+		case "message_start":
+			r = ev.Message
+		case "content_block_start":
+			if ev.Index != len(r.Content) {
+				return nil, fmt.Errorf("content block %d started out of order", ev.Index)
+			}
+			r.Content = append(r.Content, ev.ContentBlock)
+			start := toLLMContent(ev.ContentBlock)
+			if err := send(llm.StreamEvent{Index: ev.Index, Start: &start}); err != nil {
+				return nil, err
+			}
+		case "content_block_delta":
+			if ev.Index >= len(r.Content) {
+				return nil, fmt.Errorf("delta for unknown content block %d", ev.Index)
+			}
+			c := &r.Content[ev.Index]
+			out := llm.StreamEvent{Index: ev.Index}
+			switch ev.Delta.Type {
+			case "text_delta":
+				text := ev.Delta.Text
+				if c.Text != nil {
+					text = *c.Text + text
+				}
+				c.Text = &text
+				out.Text = ev.Delta.Text
+			case "thinking_delta":
+				c.Thinking += ev.Delta.Thinking
+				out.Thinking = ev.Delta.Thinking
+			case "signature_delta":
+				c.Signature += ev.Delta.Signature
+				continue
+			case "input_json_delta":
+				b := toolInputs[ev.Index]
+				if b == nil {
+					b = new(strings.Builder)
+					toolInputs[ev.Index] = b
+				}
+				b.WriteString(ev.Delta.PartialJSON)
+				out.ToolInput = ev.Delta.PartialJSON
+			default:
+				continue
+			}
+			if err := send(out); err != nil {
+				return nil, err
+			}
+		case "content_block_stop":
+			// Tool input arrives in pieces; content_block_start only had an empty placeholder.
+			if b := toolInputs[ev.Index]; b != nil && ev.Index < len(r.Content) {
+				c := &r.Content[ev.Index]
+				c.ToolInput = json.RawMessage(b.String())
+				if c.raw != nil {
+					// Unknown content is sent back verbatim, so its raw JSON needs the input as well.
+					var fields map[string]json.RawMessage
+					if err := json.Unmarshal(c.raw, &fields); err != nil {
+						return nil, err
+					}
+					fields["input"] = c.ToolInput
+					raw, err := json.Marshal(fields)
+					if err != nil {
+						return nil, fmt.Errorf("invalid input for content block %d: %w", ev.Index, err)
+					}
+					c.raw = raw
+				}
+			}
+		case "message_delta":
+			r.StopReason = ev.Delta.StopReason
+			r.StopSequence = ev.Delta.StopSequence
+			if ev.Usage != nil {
+				r.Usage.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			return &r, nil
+		case "error":
+			return nil, fmt.Errorf("anthropic stream error: %s", ev.Error)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("anthropic stream ended before message_stop")
+}
@@ -0,0 +1,203 @@
+package ant
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sketch.dev/llm"
+)
+
+// testStream is a trimmed-down stream as sent by the Anthropic API, with a text block followed by a tool use.
+const testStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_01","name":"bash","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"command\": "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"ls\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":42}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`
+
+func TestDoStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"stream":true`) {
+			t.Errorf("request did not ask for streaming: %s", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, testStream)
+	}))
+	defer srv.Close()
+
+	svc := &Service{URL: srv.URL, APIKey: "test"}
+	events, err := svc.DoStream(context.Background(), &llm.Request{
+		Messages: []llm.Message{llm.UserStringMessage("list files")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text, toolInput strings.Builder
+	var starts []llm.Content
+	var final llm.StreamEvent
+	for ev := range events {
+		if ev.Response != nil || ev.Err != nil {
+			final = ev
+			continue
+		}
+		if ev.Start != nil {
+			starts = append(starts, *ev.Start)
+		}
+		text.WriteString(ev.Text)
+		toolInput.WriteString(ev.ToolInput)
+	}
+
+	if final.Err != nil {
+		t.Fatalf("stream failed: %v", final.Err)
+	}
+	if text.String() != "Let me check." {
+		t.Errorf("streamed text = %q", text.String())
+	}
+	if toolInput.String() != `{"command": "ls"}` {
+		t.Errorf("streamed tool input = %q", toolInput.String())
+	}
+	if len(starts) != 2 || starts[0].Type != llm.ContentTypeText || starts[1].Type != llm.ContentTypeToolUse || starts[1].ToolName != "bash" {
+		t.Errorf("unexpected content block starts: %+v", starts)
+	}
+
+	resp := final.Response
+	if resp == nil {
+		t.Fatal("stream ended without a response")
+	}
+	if resp.ID != "msg_1" || resp.StopReason != llm.StopReasonToolUse {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.Usage.InputTokens != 25 || resp.Usage.OutputTokens != 42 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+	if len(resp.Content) != 2 {
+		t.Fatalf("expected 2 contents, got %d", len(resp.Content))
+	}
+	if resp.Content[0].Text != "Let me check." {
+		t.Errorf("response text = %q", resp.Content[0].Text)
+	}
+	if tu := resp.Content[1]; tu.ID != "toolu_01" || string(tu.ToolInput) != `{"command": "ls"}` {
+		t.Errorf("unexpected tool use: %+v", tu)
+	}
+}
+
+func TestDoStreamErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantStartErr bool // fails before streaming starts
+	}{
+		{name: "http error", status: http.StatusBadRequest, body: `{"type":"error"}`, wantStartErr: true},
+		{name: "stream error", status: http.StatusOK, body: "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\"}}\n\n"},
+		{name: "truncated", status: http.StatusOK, body: "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{}}\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			svc := &Service{URL: srv.URL, APIKey: "test"}
+			events, err := svc.DoStream(context.Background(), &llm.Request{
+				Messages: []llm.Message{llm.UserStringMessage("hi")},
+			})
+			if tt.wantStartErr != (err != nil) {
+				t.Fatalf("DoStream() error = %v, wantStartErr %v", err, tt.wantStartErr)
+			}
+			if err != nil {
+				return
+			}
+			var final llm.StreamEvent
+			for ev := range events {
+				final = ev
+			}
+			if final.Err == nil {
+				t.Errorf("expected the stream to end with an error, got %+v", final)
+			}
+		})
+	}
+}
+
+func TestDoStreamUnknownContent(t *testing.T) {
+	// server_tool_use isn't modeled by llm; its streamed input must end up in the block that is sent back.
+	stream := `data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{}}}
+data: {"type":"content_block_start","index":0,"content_block":{"type":"server_tool_use","id":"srvtoolu_01","name":"web_search","input":{}}}
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"query\":\"golang\"}"}}
+data: {"type":"content_block_stop","index":0}
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}
+data: {"type":"message_stop"}
+`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, stream)
+	}))
+	defer srv.Close()
+
+	svc := &Service{URL: srv.URL, APIKey: "test"}
+	events, err := svc.DoStream(context.Background(), &llm.Request{
+		Messages: []llm.Message{llm.UserStringMessage("search")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var final llm.StreamEvent
+	for ev := range events {
+		final = ev
+	}
+	if final.Response == nil {
+		t.Fatalf("stream failed: %v", final.Err)
+	}
+
+	c := final.Response.Content[0]
+	if c.Type != llm.ContentTypeUnknown {
+		t.Fatalf("expected ContentTypeUnknown, got %v", c.Type)
+	}
+	got, err := json.Marshal(fromLLMContent(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"srvtoolu_01","input":{"query":"golang"},"name":"web_search","type":"server_tool_use"}`
+	if string(got) != want {
+		t.Errorf("unknown content:\n got: %s\nwant: %s", got, want)
+	}
+}
@@ -47,6 +47,11 @@ type Request struct {
 	ToolChoice *ToolChoice
 	Tools      []*Tool
 	System     []SystemContent
+
+	// Sampling parameters; nil or empty leaves the provider's default in place.
+	Temperature   *float64
+	TopP          *float64
+	StopSequences []string
 }
 
 // Message represents a message in the conversation.
@@ -98,12 +103,12 @@ type Content struct {
 	Type ContentType
 	Text string
 
-	// Media type for image content
+	// Media type for image or document content
 	MediaType string
 
 	// for thinking
 	Thinking  string
-	Data      string
+	Data      string // also base64 data for image or document content
 	Signature string
 
 	// for tool_use
@@ -120,6 +125,9 @@ type Content struct {
 	ToolUseEndTime   *time.Time
 
 	Cache bool
+
+	// for ContentTypeUnknown: the provider's own encoding of the content, sent back as-is
+	Raw json.RawMessage
 }
 
 func StringContent(s string) Content {
@@ -170,6 +178,8 @@ const (
 	ContentTypeRedactedThinking
 	ContentTypeToolUse
 	ContentTypeToolResult
+	ContentTypeDocument // e.g. a PDF, carried base64-encoded in Data with its MediaType
+	ContentTypeUnknown  // a provider content type not modeled here, carried verbatim in Raw
 
 	ToolChoiceTypeAuto ToolChoiceType = iota // default
 	ToolChoiceTypeAny                        // any tool, but must use one
@@ -203,6 +213,23 @@ func (m *Response) ToMessage() Message {
 	}
 }
 
+// StreamEvent is an incremental update from a streaming request.
+// The final event on a stream carries either the complete Response or an Err.
+type StreamEvent struct {
+	// Index is the position in the response's Content that this event updates.
+	Index int
+	// Start is set when a new content block begins, e.g. a tool use with its ID and ToolName.
+	Start *Content
+	// Text, Thinking, and ToolInput are appended to the content at Index as they are generated.
+	// ToolInput is a fragment of JSON; it is only valid once the block is complete.
+	Text      string
+	Thinking  string
+	ToolInput string
+
+	Response *Response
+	Err      error
+}
+
 func CostUSDFromResponse(headers http.Header) float64 {
 	h := headers.Get("Skaband-Cost-Microcents")
 	if h == "" {
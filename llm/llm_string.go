@@ -31,11 +31,13 @@ func _() {
 	_ = x[ContentTypeRedactedThinking-4]
 	_ = x[ContentTypeToolUse-5]
 	_ = x[ContentTypeToolResult-6]
+	_ = x[ContentTypeDocument-7]
+	_ = x[ContentTypeUnknown-8]
 }
 
-const _ContentType_name = "ContentTypeTextContentTypeThinkingContentTypeRedactedThinkingContentTypeToolUseContentTypeToolResult"
+const _ContentType_name = "ContentTypeTextContentTypeThinkingContentTypeRedactedThinkingContentTypeToolUseContentTypeToolResultContentTypeDocumentContentTypeUnknown"
 
-var _ContentType_index = [...]uint8{0, 15, 34, 61, 79, 100}
+var _ContentType_index = [...]uint8{0, 15, 34, 61, 79, 100, 119, 137}
 
 func (i ContentType) String() string {
 	i -= 2
@@ -48,10 +50,10 @@ func _() {
 	// An "invalid array index" compiler error signifies that the constant values have changed.
 	// Re-run the stringer command to generate them again.
 	var x [1]struct{}
-	_ = x[ToolChoiceTypeAuto-7]
-	_ = x[ToolChoiceTypeAny-8]
-	_ = x[ToolChoiceTypeNone-9]
-	_ = x[ToolChoiceTypeTool-10]
+	_ = x[ToolChoiceTypeAuto-9]
+	_ = x[ToolChoiceTypeAny-10]
+	_ = x[ToolChoiceTypeNone-11]
+	_ = x[ToolChoiceTypeTool-12]
 }
 
 const _ToolChoiceType_name = "ToolChoiceTypeAutoToolChoiceTypeAnyToolChoiceTypeNoneToolChoiceTypeTool"
@@ -59,9 +61,9 @@ const _ToolChoiceType_name = "ToolChoiceTypeAutoToolChoiceTypeAnyToolChoiceTypeN
 var _ToolChoiceType_index = [...]uint8{0, 18, 35, 53, 71}
 
 func (i ToolChoiceType) String() string {
-	i -= 7
+	i -= 9
 	if i < 0 || i >= ToolChoiceType(len(_ToolChoiceType_index)-1) {
-		return "ToolChoiceType(" + strconv.FormatInt(int64(i+7), 10) + ")"
+		return "ToolChoiceType(" + strconv.FormatInt(int64(i+9), 10) + ")"
 	}
 	return _ToolChoiceType_name[_ToolChoiceType_index[i]:_ToolChoiceType_index[i+1]]
 }
@@ -69,11 +71,11 @@ func _() {
 	// An "invalid array index" compiler error signifies that the constant values have changed.
 	// Re-run the stringer command to generate them again.
 	var x [1]struct{}
-	_ = x[StopReasonStopSequence-11]
-	_ = x[StopReasonMaxTokens-12]
-	_ = x[StopReasonEndTurn-13]
-	_ = x[StopReasonToolUse-14]
-	_ = x[StopReasonRefusal-15]
+	_ = x[StopReasonStopSequence-13]
+	_ = x[StopReasonMaxTokens-14]
+	_ = x[StopReasonEndTurn-15]
+	_ = x[StopReasonToolUse-16]
+	_ = x[StopReasonRefusal-17]
 }
 
 const _StopReason_name = "StopReasonStopSequenceStopReasonMaxTokensStopReasonEndTurnStopReasonToolUseStopReasonRefusal"
@@ -81,9 +83,9 @@ const _StopReason_name = "StopReasonStopSequenceStopReasonMaxTokensStopReasonEnd
 var _StopReason_index = [...]uint8{0, 22, 41, 58, 75, 92}
 
 func (i StopReason) String() string {
-	i -= 11
+	i -= 13
 	if i < 0 || i >= StopReason(len(_StopReason_index)-1) {
-		return "StopReason(" + strconv.FormatInt(int64(i+11), 10) + ")"
+		return "StopReason(" + strconv.FormatInt(int64(i+13), 10) + ")"
 	}
 	return _StopReason_name[_StopReason_index[i]:_StopReason_index[i+1]]
 }
@@ -238,26 +238,64 @@ func (s *Server) proxyToPort(w http.ResponseWriter, r *http.Request, port string
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(target)
+	// Flush every write so dev servers that stream (SSE, long-poll, live reload)
+	// reach the browser as they produce output rather than when they finish.
+	proxy.FlushInterval = -1
 
 	// Customize the Director to modify the request
 	originalDirector := proxy.Director
 	proxy.Director = func(req *http.Request) {
+		originalHost := req.Host
 		originalDirector(req)
 		// Set the target host
 		req.URL.Host = target.Host
 		req.URL.Scheme = target.Scheme
 		req.Host = target.Host
+
+		// Tell the backend how it was reached. ReverseProxy appends the client IP
+		// to X-Forwarded-For itself; values set by a proxy in front of us are kept.
+		if req.Header.Get("X-Forwarded-Host") == "" {
+			req.Header.Set("X-Forwarded-Host", originalHost)
+		}
+		if req.Header.Get("X-Forwarded-Proto") == "" {
+			proto := "http"
+			if req.TLS != nil {
+				proto = "https"
+			}
+			req.Header.Set("X-Forwarded-Proto", proto)
+		}
 	}
 
 	// Handle proxy errors
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
 		slog.Error("Proxy error", "error", err, "target", target.String(), "port", port)
-		http.Error(w, "Proxy error: "+err.Error(), http.StatusBadGateway)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprintf(w, proxyErrorPage, port, port, port, html.EscapeString(err.Error()))
 	}
 
 	proxy.ServeHTTP(w, r)
 }
 
+// proxyErrorPage is served when proxyToPort can't reach the service on the requested port.
+// Its arguments are the port (three times) and the HTML-escaped proxy error.
+const proxyErrorPage = `<!DOCTYPE html>
+<html>
+<head>
+<title>Nothing on port %s</title>
+<style>body { font-family: sans-serif; max-width: 40em; margin: 4em auto; }</style>
+</head>
+<body>
+<h1>502 Bad Gateway</h1>
+<p>Sketch tried to forward this request to port %s in the container, but nothing answered.
+The service is probably not running yet, has crashed, or is listening on a different port.</p>
+<p>Start the service on port %s and reload this page.</p>
+<pre>%s</pre>
+</body>
+</html>
+`
+
 // New creates a new HTTP server.
 func New(agent loop.CodingAgent, logFile *os.File) (*Server, error) {
 	s := &Server{
@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"context"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -605,6 +607,151 @@ func TestParsePortProxyHost(t *testing.T) {
 	}
 }
 
+// TestPortProxyForwardedHeaders tests that proxied requests carry X-Forwarded-* headers
+func TestPortProxyForwardedHeaders(t *testing.T) {
+	var got http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer backend.Close()
+	port := strconv.Itoa(backend.Listener.Addr().(*net.TCPAddr).Port)
+
+	s, err := server.New(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		header    http.Header
+		wantFor   string
+		wantHost  string
+		wantProto string
+	}{
+		{
+			name:      "direct",
+			wantFor:   "192.0.2.1",
+			wantHost:  "p" + port + ".localhost:8080",
+			wantProto: "http",
+		},
+		{
+			name: "behind another proxy",
+			header: http.Header{
+				"X-Forwarded-For":   {"203.0.113.7"},
+				"X-Forwarded-Host":  {"sketch.example.com"},
+				"X-Forwarded-Proto": {"https"},
+			},
+			wantFor:   "203.0.113.7, 192.0.2.1",
+			wantHost:  "sketch.example.com",
+			wantProto: "https",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Host = "p" + port + ".localhost:8080"
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+			}
+			if v := got.Get("X-Forwarded-For"); v != tt.wantFor {
+				t.Errorf("X-Forwarded-For = %q, want %q", v, tt.wantFor)
+			}
+			if v := got.Get("X-Forwarded-Host"); v != tt.wantHost {
+				t.Errorf("X-Forwarded-Host = %q, want %q", v, tt.wantHost)
+			}
+			if v := got.Get("X-Forwarded-Proto"); v != tt.wantProto {
+				t.Errorf("X-Forwarded-Proto = %q, want %q", v, tt.wantProto)
+			}
+		})
+	}
+}
+
+// TestPortProxyStreaming tests that streamed responses are passed through before the backend finishes
+func TestPortProxyStreaming(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// With a known length, ReverseProxy only flushes as it goes if FlushInterval says to.
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", strconv.Itoa(len("first chunk\nlast chunk\n")))
+		io.WriteString(w, "first chunk\n")
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "last chunk\n")
+	}))
+	defer backend.Close()
+	defer close(release)
+	port := strconv.Itoa(backend.Listener.Addr().(*net.TCPAddr).Port)
+
+	s, err := server.New(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	front := httptest.NewServer(s)
+	defer front.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", front.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "p" + port + ".localhost"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading first chunk: %v", err)
+	}
+	if line != "first chunk\n" {
+		t.Errorf("got %q, want %q", line, "first chunk\n")
+	}
+}
+
+// TestPortProxyBackendDown tests that proxying to a port with nothing listening serves a 502 page
+func TestPortProxyBackendDown(t *testing.T) {
+	// Grab a free port and release it so that nothing is listening there.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	ln.Close()
+
+	s, err := server.New(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "p" + port + ".localhost"
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("got status %d, want %d", rr.Code, http.StatusBadGateway)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("got Content-Type %q, want text/html", ct)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "port "+port) {
+		t.Errorf("response should name port %s, got: %s", port, body)
+	}
+	if !strings.Contains(body, "connection refused") {
+		t.Errorf("response should include the proxy error, got: %s", body)
+	}
+}
+
 // TestStateEndpointIncludesPorts tests that the /state endpoint includes port information
 func TestStateEndpointIncludesPorts(t *testing.T) {
 	mockAgent := &mockAgent{
@@ -1,12 +1,20 @@
 package mcp
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/mark3labs/mcp-go/client"
@@ -23,21 +31,108 @@ type ServerConfig struct {
 	Command string            `json:"command,omitempty"` // for stdio
 	Args    []string          `json:"args,omitempty"`    // for stdio
 	Env     map[string]string `json:"env,omitempty"`     // for stdio
+	Dir     string            `json:"dir,omitempty"`     // working directory for stdio; defaults to the current directory
 	Headers map[string]string `json:"headers,omitempty"` // for http/sse
+
+	// ToolTimeout bounds each tool call, as a Go duration string such as "5m".
+	// Defaults to DefaultToolTimeout.
+	ToolTimeout string `json:"tool_timeout,omitempty"`
+
+	// Tools, if non-empty, lists the only tools to use from this server.
+	// ExcludeTools lists tools to drop. Both accept path.Match glob patterns.
+	// They also apply to the read_resource tool added for servers that offer resources.
+	Tools        []string `json:"tools,omitempty"`
+	ExcludeTools []string `json:"exclude_tools,omitempty"`
+
+	// MaxConcurrency limits how many calls to this server may run at once; further calls wait.
+	// Defaults to DefaultMaxConcurrency.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+}
+
+// DefaultToolTimeout is the per-call timeout for MCP tools when a server config does not set one.
+const DefaultToolTimeout = 120 * time.Second
+
+// DefaultMaxConcurrency is the number of concurrent calls allowed per MCP server when a server config does not set one.
+const DefaultMaxConcurrency = 4
+
+// toolTimeout returns the configured tool call timeout.
+// ParseServerConfigs has already validated ToolTimeout, so parse errors fall back to the default.
+func (c ServerConfig) toolTimeout() time.Duration {
+	if c.ToolTimeout == "" {
+		return DefaultToolTimeout
+	}
+	d, err := time.ParseDuration(c.ToolTimeout)
+	if err != nil || d <= 0 {
+		return DefaultToolTimeout
+	}
+	return d
+}
+
+// includeTool reports whether the tool named name passes the config's Tools and ExcludeTools filters.
+func (c ServerConfig) includeTool(name string) bool {
+	if len(c.Tools) > 0 && !matchAny(c.Tools, name) {
+		return false
+	}
+	return !matchAny(c.ExcludeTools, name)
+}
+
+// matchAny reports whether name matches any of the glob patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // MCPManager manages multiple MCP server connections
 type MCPManager struct {
 	mu      sync.RWMutex
 	clients map[string]*MCPClientWrapper
+	status  map[string]error // last connection result per server name; nil means connected
 }
 
 // MCPClientWrapper wraps an MCP client connection
 type MCPClientWrapper struct {
 	name   string
 	config ServerConfig
+	mu     sync.Mutex // protects client, which is replaced on reconnect, and the reconnect state
 	client *client.Client
 	tools  []*llm.Tool
+
+	reconnecting chan struct{} // closed when the reconnect in progress finishes; nil if there is none
+	reconnectErr error         // the error from the last reconnect
+
+	// resources and prompts are listed once, when the server first connects.
+	resources []mcp.Resource
+	prompts   []mcp.Prompt
+
+	sem chan struct{} // bounds concurrent calls to this server
+}
+
+// MCPResource describes a resource offered by an MCP server.
+type MCPResource struct {
+	ServerName  string
+	URI         string
+	Name        string
+	Description string
+	MIMEType    string
+}
+
+// MCPPrompt describes a prompt template offered by an MCP server.
+type MCPPrompt struct {
+	ServerName  string
+	Name        string
+	Description string
+	Arguments   []MCPPromptArgument
+}
+
+// MCPPromptArgument describes an argument used to fill in an MCP prompt template.
+type MCPPromptArgument struct {
+	Name        string
+	Description string
+	Required    bool
 }
 
 // MCPServerConnection represents a successful MCP server connection with its tools
@@ -51,6 +146,7 @@ type MCPServerConnection struct {
 func NewMCPManager() *MCPManager {
 	return &MCPManager{
 		clients: make(map[string]*MCPClientWrapper),
+		status:  make(map[string]error),
 	}
 }
 
@@ -75,12 +171,36 @@ func ParseServerConfigs(ctx context.Context, configs []string) ([]ServerConfig,
 			errors = append(errors, fmt.Errorf("config %d: name is required", i))
 			continue
 		}
+		if config.ToolTimeout != "" {
+			if d, err := time.ParseDuration(config.ToolTimeout); err != nil || d <= 0 {
+				errors = append(errors, fmt.Errorf("config %d: invalid tool_timeout %q: must be a positive duration", i, config.ToolTimeout))
+				continue
+			}
+		}
+		if config.MaxConcurrency < 0 {
+			errors = append(errors, fmt.Errorf("config %d: max_concurrency must not be negative", i))
+			continue
+		}
+		if err := validatePatterns(append(slices.Clone(config.Tools), config.ExcludeTools...)); err != nil {
+			errors = append(errors, fmt.Errorf("config %d: %w", i, err))
+			continue
+		}
 		serverConfigs = append(serverConfigs, config)
 	}
 
 	return serverConfigs, errors
 }
 
+// validatePatterns checks that each tool filter pattern is a valid glob.
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
 // ConnectToServerConfigs connects to multiple parsed MCP server configs in parallel
 func (m *MCPManager) ConnectToServerConfigs(ctx context.Context, serverConfigs []ServerConfig, timeout time.Duration, existingErrors []error) ([]MCPServerConnection, []error) {
 	if len(serverConfigs) == 0 {
@@ -88,9 +208,16 @@ func (m *MCPManager) ConnectToServerConfigs(ctx context.Context, serverConfigs [
 	}
 
 	slog.InfoContext(ctx, "Connecting to MCP servers", "count", len(serverConfigs), "timeout", timeout)
+	serverConfigs = m.uniqueServerNames(ctx, serverConfigs)
+	m.mu.Lock()
+	for _, config := range serverConfigs {
+		delete(m.status, config.Name)
+	}
+	m.mu.Unlock()
 
 	// Connect to servers in parallel using sync.WaitGroup
 	type result struct {
+		index         int
 		tools         []*llm.Tool
 		err           error
 		serverName    string
@@ -101,27 +228,29 @@ func (m *MCPManager) ConnectToServerConfigs(ctx context.Context, serverConfigs [
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	for _, config := range serverConfigs {
-		go func(cfg ServerConfig) {
+	for i, config := range serverConfigs {
+		go func(i int, cfg ServerConfig) {
 			slog.InfoContext(ctx, "Connecting to MCP server", "server", cfg.Name, "type", cfg.Type, "url", cfg.URL, "command", cfg.Command)
 			tools, originalToolNames, err := m.connectToServerWithNames(ctxWithTimeout, cfg)
 			results <- result{
+				index:         i,
 				tools:         tools,
 				err:           err,
 				serverName:    cfg.Name,
 				originalTools: originalToolNames,
 			}
-		}(config)
+		}(i, config)
 	}
 
-	// Collect results
-	var connections []MCPServerConnection
+	// Collect results, indexed by config so that connections are reported in config order
+	connected := make([]*MCPServerConnection, len(serverConfigs))
 	errors := make([]error, 0, len(existingErrors))
 	errors = append(errors, existingErrors...)
 
 	for range len(serverConfigs) {
 		select {
 		case res := <-results:
+			m.setStatus(res.serverName, res.err)
 			if res.err != nil {
 				slog.ErrorContext(ctx, "Failed to connect to MCP server", "server", res.serverName, "error", res.err)
 				errors = append(errors, fmt.Errorf("MCP server %q: %w", res.serverName, res.err))
@@ -131,7 +260,7 @@ func (m *MCPManager) ConnectToServerConfigs(ctx context.Context, serverConfigs [
 					Tools:      res.tools,
 					ToolNames:  res.originalTools,
 				}
-				connections = append(connections, connection)
+				connected[res.index] = &connection
 				slog.InfoContext(ctx, "Successfully connected to MCP server", "server", res.serverName, "tools", len(res.tools), "tool_names", res.originalTools)
 			}
 		case <-ctxWithTimeout.Done():
@@ -139,10 +268,94 @@ func (m *MCPManager) ConnectToServerConfigs(ctx context.Context, serverConfigs [
 			break
 		}
 	}
+	m.mu.Lock()
+	for _, config := range serverConfigs {
+		if _, ok := m.status[config.Name]; !ok {
+			m.status[config.Name] = fmt.Errorf("timeout connecting to MCP server")
+		}
+	}
+	m.mu.Unlock()
+
+	var connections []MCPServerConnection
+	for _, conn := range connected {
+		if conn != nil {
+			connections = append(connections, *conn)
+		}
+	}
+	// Config order makes tool name disambiguation deterministic.
+	uniqueToolNames(ctx, connections)
 
 	return connections, errors
 }
 
+// setStatus records the result of the latest connection attempt to serverName.
+func (m *MCPManager) setStatus(serverName string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status[serverName] = err
+}
+
+// ConnectionStatus reports the result of the latest connection attempt to each MCP server,
+// keyed by server name. A nil error means the server is connected.
+func (m *MCPManager) ConnectionStatus() map[string]error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return maps.Clone(m.status)
+}
+
+// uniqueServerNames returns configs with duplicate server names, including names of
+// already-connected servers, disambiguated with a numeric suffix.
+func (m *MCPManager) uniqueServerNames(ctx context.Context, configs []ServerConfig) []ServerConfig {
+	m.mu.RLock()
+	taken := make(map[string]bool, len(m.clients)+len(configs))
+	for name := range m.clients {
+		taken[name] = true
+	}
+	m.mu.RUnlock()
+
+	out := make([]ServerConfig, len(configs))
+	for i, config := range configs {
+		name := uniqueName(config.Name, taken)
+		if name != config.Name {
+			slog.WarnContext(ctx, "Duplicate MCP server name; renaming", "server", config.Name, "renamed", name)
+			config.Name = name
+		}
+		taken[name] = true
+		out[i] = config
+	}
+	return out
+}
+
+// uniqueToolNames renames tools whose names collide with tools from earlier connections.
+// Different server and tool names can produce the same prefixed name,
+// e.g. server "a_b" with tool "c" and server "a" with tool "b_c".
+func uniqueToolNames(ctx context.Context, connections []MCPServerConnection) {
+	taken := make(map[string]bool)
+	for _, conn := range connections {
+		for _, tool := range conn.Tools {
+			name := uniqueName(tool.Name, taken)
+			if name != tool.Name {
+				slog.WarnContext(ctx, "Duplicate MCP tool name; renaming", "server", conn.ServerName, "tool", tool.Name, "renamed", name)
+				tool.Name = name
+			}
+			taken[name] = true
+		}
+	}
+}
+
+// uniqueName returns name if it is not taken, and otherwise name with the smallest numeric suffix that is not taken.
+func uniqueName(name string, taken map[string]bool) string {
+	if !taken[name] {
+		return name
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s_%d", name, n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
 // connectToServerWithNames connects to a single MCP server and returns tools with original names
 func (m *MCPManager) connectToServerWithNames(ctx context.Context, config ServerConfig) ([]*llm.Tool, []string, error) {
 	tools, err := m.connectToServer(ctx, config)
@@ -153,13 +366,8 @@ func (m *MCPManager) connectToServerWithNames(ctx context.Context, config Server
 	// Extract original tool names (remove server prefix)
 	originalNames := make([]string, len(tools))
 	for i, tool := range tools {
-		// Tool names are in format "servername_toolname"
-		parts := strings.SplitN(tool.Name, "_", 2)
-		if len(parts) == 2 {
-			originalNames[i] = parts[1]
-		} else {
-			originalNames[i] = tool.Name // fallback if no prefix
-		}
+		// Tool names are in format "servername_toolname", and servername may itself contain underscores
+		originalNames[i] = strings.TrimPrefix(tool.Name, config.Name+"_")
 	}
 
 	return tools, originalNames, nil
@@ -167,23 +375,83 @@ func (m *MCPManager) connectToServerWithNames(ctx context.Context, config Server
 
 // connectToServer connects to a single MCP server
 func (m *MCPManager) connectToServer(ctx context.Context, config ServerConfig) ([]*llm.Tool, error) {
-	var mcpClient *client.Client
-	var err error
+	mcpClient, err := startClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get available tools
+	toolsReq := mcp.ListToolsRequest{}
+	toolsResp, err := mcpClient.ListTools(ctx, toolsReq)
+	if err != nil {
+		mcpClient.Close()
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
 
-	// Convert environment variables to []string format
-	var envVars []string
-	for k, v := range config.Env {
-		envVars = append(envVars, k+"="+v)
+	var mcpTools []mcp.Tool
+	for _, tool := range toolsResp.Tools {
+		if config.includeTool(tool.Name) {
+			mcpTools = append(mcpTools, tool)
+		}
+	}
+	if dropped := len(toolsResp.Tools) - len(mcpTools); dropped > 0 {
+		slog.InfoContext(ctx, "Filtered MCP server tools", "server", config.Name, "dropped", dropped, "kept", len(mcpTools))
 	}
 
+	// Convert MCP tools to llm.Tool
+	llmTools, err := m.convertMCPTools(config, mcpTools)
+	if err != nil {
+		mcpClient.Close()
+		return nil, fmt.Errorf("failed to convert tools: %w", err)
+	}
+
+	// Resources are optional; a server that fails to list them still contributes its tools.
+	resources, err := listResources(ctx, mcpClient)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to list MCP server resources", "server", config.Name, "error", err)
+	}
+	if len(resources) > 0 && config.includeTool("read_resource") {
+		llmTools = append(llmTools, m.readResourceTool(config, resources))
+	}
+
+	prompts, err := listPrompts(ctx, mcpClient)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to list MCP server prompts", "server", config.Name, "error", err)
+	}
+
+	// Store the client
+	clientWrapper := &MCPClientWrapper{
+		name:      config.Name,
+		config:    config,
+		client:    mcpClient,
+		tools:     llmTools,
+		resources: resources,
+		prompts:   prompts,
+		sem:       make(chan struct{}, cmp.Or(config.MaxConcurrency, DefaultMaxConcurrency)),
+	}
+
+	m.mu.Lock()
+	m.clients[config.Name] = clientWrapper
+	m.mu.Unlock()
+
+	return llmTools, nil
+}
+
+// startClient creates, starts, and initializes a client for a single MCP server.
+func startClient(ctx context.Context, config ServerConfig) (*client.Client, error) {
+	var mcpClient *client.Client
+	var err error
+
 	switch config.Type {
 	case "stdio", "":
 		if config.Command == "" {
 			return nil, fmt.Errorf("command is required for stdio transport")
 		}
-		mcpClient, err = client.NewStdioMCPClient(config.Command, envVars, config.Args...)
-		// TODO: Get the transport, cast it to *transport.Stdio, and start a goroutine to pipe stderr from the subprocess
-		// to our subprocess, but with each line prefixed with the server name.
+		var t transport.Interface
+		t, err = startStdio(config)
+		if err == nil {
+			mcpClient = client.NewClient(t)
+		}
 	case "http":
 		if config.URL == "" {
 			return nil, fmt.Errorf("URL is required for HTTP transport")
@@ -211,8 +479,10 @@ func (m *MCPManager) connectToServer(ctx context.Context, config ServerConfig) (
 		return nil, fmt.Errorf("failed to create MCP client: %w", err)
 	}
 
-	// Start the client first
-	if err := mcpClient.Start(ctx); err != nil {
+	// ctx only bounds connection setup, but the transport lives as long as the client,
+	// so it must not be cancelled when setup finishes.
+	if err := mcpClient.Start(context.WithoutCancel(ctx)); err != nil {
+		mcpClient.Close()
 		return nil, fmt.Errorf("failed to start MCP client: %w", err)
 	}
 
@@ -228,40 +498,357 @@ func (m *MCPManager) connectToServer(ctx context.Context, config ServerConfig) (
 		},
 	}
 	if _, err := mcpClient.Initialize(ctx, initReq); err != nil {
+		mcpClient.Close()
 		return nil, fmt.Errorf("failed to initialize MCP client: %w", err)
 	}
 
-	// Get available tools
-	toolsReq := mcp.ListToolsRequest{}
-	toolsResp, err := mcpClient.ListTools(ctx, toolsReq)
+	return mcpClient, nil
+}
+
+// reconnectTimeout bounds connecting to a server again after its transport fails.
+var reconnectTimeout = 10 * time.Second
+
+// reconnect replaces the client for serverName with a fresh connection.
+// failed is the client whose call failed; if another caller has already
+// replaced it, reconnect returns the replacement without reconnecting again,
+// and if another caller is reconnecting, reconnect waits for it.
+func (m *MCPManager) reconnect(ctx context.Context, serverName string, failed *client.Client) (*client.Client, error) {
+	m.mu.RLock()
+	wrapper := m.clients[serverName]
+	m.mu.RUnlock()
+	if wrapper == nil {
+		return nil, fmt.Errorf("MCP server %q is not connected", serverName)
+	}
+
+	wrapper.mu.Lock()
+	if wrapper.client != failed {
+		defer wrapper.mu.Unlock()
+		return wrapper.client, nil
+	}
+	if done := wrapper.reconnecting; done != nil {
+		wrapper.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		wrapper.mu.Lock()
+		defer wrapper.mu.Unlock()
+		if wrapper.client == failed {
+			return nil, wrapper.reconnectErr
+		}
+		return wrapper.client, nil
+	}
+	done := make(chan struct{})
+	wrapper.reconnecting = done
+	wrapper.mu.Unlock()
+
+	// Connect without holding wrapper.mu, so that a server that is slow to start
+	// does not block callers that only need the current client.
+	slog.WarnContext(ctx, "Reconnecting to MCP server", "server", serverName)
+	failed.Close()
+	connectCtx, cancel := context.WithTimeout(ctx, reconnectTimeout)
+	defer cancel()
+	mcpClient, err := startClient(connectCtx, wrapper.config)
+
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	defer close(done)
+	wrapper.reconnecting = nil
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tools: %w", err)
+		wrapper.reconnectErr = fmt.Errorf("failed to reconnect to MCP server %q: %w", serverName, err)
+		m.setStatus(serverName, wrapper.reconnectErr)
+		return nil, wrapper.reconnectErr
+	}
+	m.mu.RLock()
+	closed := m.clients[serverName] != wrapper
+	m.mu.RUnlock()
+	if closed {
+		// Close ran while we were connecting, so it could not close the new client.
+		mcpClient.Close()
+		wrapper.reconnectErr = fmt.Errorf("MCP server %q is not connected", serverName)
+		return nil, wrapper.reconnectErr
 	}
+	wrapper.client = mcpClient
+	m.setStatus(serverName, nil)
+	return mcpClient, nil
+}
 
-	// Convert MCP tools to llm.Tool
-	llmTools, err := m.convertMCPTools(config.Name, mcpClient, toolsResp.Tools)
+// currentClient returns the live client for serverName.
+func (m *MCPManager) currentClient(serverName string) (*client.Client, error) {
+	m.mu.RLock()
+	wrapper := m.clients[serverName]
+	m.mu.RUnlock()
+	if wrapper == nil {
+		return nil, fmt.Errorf("MCP server %q is not connected", serverName)
+	}
+	wrapper.mu.Lock()
+	defer wrapper.mu.Unlock()
+	return wrapper.client, nil
+}
+
+// pingTimeout bounds the liveness check made after a tool call times out.
+var pingTimeout = 5 * time.Second
+
+// reapIfUnresponsive closes the connection to a stdio server that does not answer a ping,
+// which terminates its process. The next call to the server reconnects.
+func (m *MCPManager) reapIfUnresponsive(ctx context.Context, serverName string) {
+	mcpClient, err := m.currentClient(serverName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert tools: %w", err)
+		return
 	}
+	if _, ok := mcpClient.GetTransport().(*stdioTransport); !ok {
+		return
+	}
+	pingCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pingTimeout)
+	defer cancel()
+	pingErr := mcpClient.Ping(pingCtx)
+	if pingErr == nil {
+		return
+	}
+	slog.WarnContext(ctx, "MCP server unresponsive after tool call timeout; terminating it", "server", serverName, "error", pingErr)
+	mcpClient.Close()
+}
 
-	// Store the client
-	clientWrapper := &MCPClientWrapper{
-		name:   config.Name,
-		config: config,
-		client: mcpClient,
-		tools:  llmTools,
+// isTransportError reports whether err indicates that the connection to an MCP server is gone,
+// such as a stdio server process that has exited or an HTTP server that is no longer listening.
+func isTransportError(err error) bool {
+	return errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, os.ErrClosed)
+}
+
+// listResources lists the resources offered by an MCP server, if it supports resources.
+func listResources(ctx context.Context, mcpClient *client.Client) ([]mcp.Resource, error) {
+	if mcpClient.GetServerCapabilities().Resources == nil {
+		return nil, nil
+	}
+	resp, err := mcpClient.ListResources(ctx, mcp.ListResourcesRequest{})
+	if err != nil {
+		return nil, err
 	}
+	return resp.Resources, nil
+}
 
-	m.mu.Lock()
-	m.clients[config.Name] = clientWrapper
-	m.mu.Unlock()
+// listPrompts lists the prompts offered by an MCP server, if it supports prompts.
+func listPrompts(ctx context.Context, mcpClient *client.Client) ([]mcp.Prompt, error) {
+	if mcpClient.GetServerCapabilities().Prompts == nil {
+		return nil, nil
+	}
+	resp, err := mcpClient.ListPrompts(ctx, mcp.ListPromptsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Prompts, nil
+}
 
-	return llmTools, nil
+// maxListedResources caps how many resources the read_resource tool description lists,
+// since the description is sent with every request.
+const maxListedResources = 50
+
+// readResourceTool returns a tool that lets the model read resources from an MCP server.
+// Its description lists resources as of connection time; the tool reads any URI the server accepts.
+func (m *MCPManager) readResourceTool(config ServerConfig, resources []mcp.Resource) *llm.Tool {
+	var desc strings.Builder
+	fmt.Fprintf(&desc, "Read a resource from the %s MCP server by its exact URI. Resources offered when the server connected:\n", config.Name)
+	for _, r := range resources[:min(len(resources), maxListedResources)] {
+		fmt.Fprintf(&desc, "- %s: %s", r.URI, r.Name)
+		if r.Description != "" {
+			fmt.Fprintf(&desc, " (%s)", r.Description)
+		}
+		desc.WriteString("\n")
+	}
+	if more := len(resources) - maxListedResources; more > 0 {
+		fmt.Fprintf(&desc, "...and %d more.\n", more)
+	}
+
+	return &llm.Tool{
+		Name:        fmt.Sprintf("%s_read_resource", config.Name),
+		Description: desc.String(),
+		InputSchema: llm.MustSchema(`{
+  "type": "object",
+  "required": ["uri"],
+  "properties": {
+    "uri": {
+      "type": "string",
+      "description": "URI of the resource to read"
+    }
+  }
+}`),
+		Run: func(ctx context.Context, input json.RawMessage) ([]llm.Content, error) {
+			var in struct {
+				URI string `json:"uri"`
+			}
+			if err := json.Unmarshal(input, &in); err != nil {
+				return nil, fmt.Errorf("failed to parse tool arguments: %w", err)
+			}
+			return m.ReadResource(ctx, config.Name, in.URI)
+		},
+	}
+}
+
+// GetAllResources returns the resources offered by all connected MCP servers, ordered by server name.
+func (m *MCPManager) GetAllResources() []MCPResource {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []MCPResource
+	for _, name := range slices.Sorted(maps.Keys(m.clients)) {
+		for _, r := range m.clients[name].resources {
+			out = append(out, MCPResource{
+				ServerName:  name,
+				URI:         r.URI,
+				Name:        r.Name,
+				Description: r.Description,
+				MIMEType:    r.MIMEType,
+			})
+		}
+	}
+	return out
+}
+
+// ReadResource reads the resource at uri from the named MCP server.
+func (m *MCPManager) ReadResource(ctx context.Context, serverName, uri string) ([]llm.Content, error) {
+	timeout, err := m.toolTimeout(serverName)
+	if err != nil {
+		return nil, err
+	}
+	var result *mcp.ReadResourceResult
+	err = m.withClient(ctx, serverName, func(mcpClient *client.Client) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		req := mcp.ReadResourceRequest{}
+		req.Params.URI = uri
+		var err error
+		result, err = mcpClient.ReadResource(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MCP resource read failed: %w", err)
+	}
+
+	var contents []mcp.Content
+	for _, rc := range result.Contents {
+		if blob, ok := rc.(mcp.BlobResourceContents); ok && strings.HasPrefix(blob.MIMEType, "image/") {
+			contents = append(contents, mcp.NewImageContent(blob.Blob, blob.MIMEType))
+			continue
+		}
+		contents = append(contents, mcp.NewEmbeddedResource(rc))
+	}
+	return convertMCPContent(contents), nil
+}
+
+// GetAllPrompts returns the prompts offered by all connected MCP servers, ordered by server name.
+func (m *MCPManager) GetAllPrompts() []MCPPrompt {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []MCPPrompt
+	for _, name := range slices.Sorted(maps.Keys(m.clients)) {
+		for _, p := range m.clients[name].prompts {
+			prompt := MCPPrompt{
+				ServerName:  name,
+				Name:        p.Name,
+				Description: p.Description,
+			}
+			for _, arg := range p.Arguments {
+				prompt.Arguments = append(prompt.Arguments, MCPPromptArgument{
+					Name:        arg.Name,
+					Description: arg.Description,
+					Required:    arg.Required,
+				})
+			}
+			out = append(out, prompt)
+		}
+	}
+	return out
+}
+
+// GetPrompt renders the named prompt from an MCP server with the given arguments.
+func (m *MCPManager) GetPrompt(ctx context.Context, serverName, name string, args map[string]string) ([]llm.Message, error) {
+	timeout, err := m.toolTimeout(serverName)
+	if err != nil {
+		return nil, err
+	}
+	var result *mcp.GetPromptResult
+	err = m.withClient(ctx, serverName, func(mcpClient *client.Client) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		req := mcp.GetPromptRequest{}
+		req.Params.Name = name
+		req.Params.Arguments = args
+		var err error
+		result, err = mcpClient.GetPrompt(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MCP prompt get failed: %w", err)
+	}
+
+	messages := make([]llm.Message, 0, len(result.Messages))
+	for _, pm := range result.Messages {
+		role := llm.MessageRoleUser
+		if pm.Role == mcp.RoleAssistant {
+			role = llm.MessageRoleAssistant
+		}
+		messages = append(messages, llm.Message{
+			Role:    role,
+			Content: convertMCPContent([]mcp.Content{pm.Content}),
+		})
+	}
+	return messages, nil
+}
+
+// toolTimeout returns the tool call timeout configured for serverName.
+func (m *MCPManager) toolTimeout(serverName string) (time.Duration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	wrapper := m.clients[serverName]
+	if wrapper == nil {
+		return 0, fmt.Errorf("MCP server %q is not connected", serverName)
+	}
+	return wrapper.config.toolTimeout(), nil
+}
+
+// withClient calls fn with the current client for serverName.
+// If fn fails because the server went away, withClient reconnects and calls fn once more.
+// Calls wait while the server's concurrency limit is reached.
+func (m *MCPManager) withClient(ctx context.Context, serverName string, fn func(*client.Client) error) error {
+	m.mu.RLock()
+	wrapper := m.clients[serverName]
+	m.mu.RUnlock()
+	if wrapper == nil {
+		return fmt.Errorf("MCP server %q is not connected", serverName)
+	}
+	select {
+	case wrapper.sem <- struct{}{}:
+		defer func() { <-wrapper.sem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	mcpClient, err := m.currentClient(serverName)
+	if err != nil {
+		return err
+	}
+	err = fn(mcpClient)
+	if err == nil || !isTransportError(err) {
+		return err
+	}
+	mcpClient, rerr := m.reconnect(ctx, serverName, mcpClient)
+	if rerr != nil {
+		return errors.Join(err, rerr)
+	}
+	return fn(mcpClient)
 }
 
 // convertMCPTools converts MCP tools to llm.Tool format
-func (m *MCPManager) convertMCPTools(serverName string, mcpClient *client.Client, mcpTools []mcp.Tool) ([]*llm.Tool, error) {
+func (m *MCPManager) convertMCPTools(config ServerConfig, mcpTools []mcp.Tool) ([]*llm.Tool, error) {
 	var llmTools []*llm.Tool
+	timeout := config.toolTimeout()
 
 	for _, mcpTool := range mcpTools {
 		// Convert the input schema
@@ -271,19 +858,27 @@ func (m *MCPManager) convertMCPTools(serverName string, mcpClient *client.Client
 		}
 
 		llmTool := &llm.Tool{
-			Name:        fmt.Sprintf("%s_%s", serverName, mcpTool.Name),
+			Name:        fmt.Sprintf("%s_%s", config.Name, mcpTool.Name),
 			Description: mcpTool.Description,
 			InputSchema: json.RawMessage(schemaBytes),
-			Run: func(toolName string, client *client.Client) func(ctx context.Context, input json.RawMessage) ([]llm.Content, error) {
+			Run: func(toolName string) func(ctx context.Context, input json.RawMessage) ([]llm.Content, error) {
 				return func(ctx context.Context, input json.RawMessage) ([]llm.Content, error) {
-					result, err := m.executeMCPTool(ctx, client, toolName, input)
+					var result []mcp.Content
+					err := m.withClient(ctx, config.Name, func(mcpClient *client.Client) error {
+						var err error
+						result, err = m.executeMCPTool(ctx, mcpClient, toolName, input, timeout)
+						return err
+					})
+					if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+						// The call hit its own timeout; make sure the server hasn't hung.
+						m.reapIfUnresponsive(ctx, config.Name)
+					}
 					if err != nil {
 						return nil, err
 					}
-					// Convert result to llm.Content
-					return []llm.Content{llm.StringContent(fmt.Sprintf("%v", result))}, nil
+					return convertMCPContent(result), nil
 				}
-			}(mcpTool.Name, mcpClient),
+			}(mcpTool.Name),
 		}
 
 		llmTools = append(llmTools, llmTool)
@@ -292,11 +887,51 @@ func (m *MCPManager) convertMCPTools(serverName string, mcpClient *client.Client
 	return llmTools, nil
 }
 
+// convertMCPContent converts MCP tool result content to llm.Content.
+// Images are passed through as image content so the model can see them.
+func convertMCPContent(contents []mcp.Content) []llm.Content {
+	var out []llm.Content
+	for _, c := range contents {
+		switch c := c.(type) {
+		case mcp.TextContent:
+			out = append(out, llm.StringContent(c.Text))
+		case mcp.ImageContent:
+			// Only JPEG and PNG are currently mapped to image blocks by the llm services.
+			if c.MIMEType == "image/jpeg" || c.MIMEType == "image/png" {
+				out = append(out, llm.Content{
+					Type:      llm.ContentTypeText, // Will be mapped to image in content array
+					MediaType: c.MIMEType,
+					Data:      c.Data,
+				})
+			} else {
+				out = append(out, llm.StringContent(fmt.Sprintf("[Image: %s, %d bytes of base64 data]", c.MIMEType, len(c.Data))))
+			}
+		case mcp.EmbeddedResource:
+			if text, ok := c.Resource.(mcp.TextResourceContents); ok {
+				out = append(out, llm.StringContent(text.Text))
+				continue
+			}
+			out = append(out, llm.StringContent(mcpContentJSON(c)))
+		default:
+			out = append(out, llm.StringContent(mcpContentJSON(c)))
+		}
+	}
+	return out
+}
+
+// mcpContentJSON renders MCP content that has no llm.Content equivalent as JSON text.
+func mcpContentJSON(c mcp.Content) string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("%v", c)
+	}
+	return string(b)
+}
+
 // executeMCPTool executes an MCP tool call
-func (m *MCPManager) executeMCPTool(ctx context.Context, mcpClient *client.Client, toolName string, input json.RawMessage) (any, error) {
+func (m *MCPManager) executeMCPTool(ctx context.Context, mcpClient *client.Client, toolName string, input json.RawMessage, timeout time.Duration) ([]mcp.Content, error) {
 	// Add timeout for tool execution
-	// TODO: Expose the timeout as a tool call argument.
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, 120*time.Second)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Parse input arguments
@@ -318,6 +953,20 @@ func (m *MCPManager) executeMCPTool(ctx context.Context, mcpClient *client.Clien
 	if err != nil {
 		return nil, fmt.Errorf("MCP tool call failed: %w", err)
 	}
+	// Tools report failures such as invalid arguments in the result rather than as protocol errors.
+	// Return them as errors so the model sees that the tool call failed.
+	if resp.IsError {
+		var msgs []string
+		for _, c := range resp.Content {
+			if text, ok := c.(mcp.TextContent); ok {
+				msgs = append(msgs, text.Text)
+			}
+		}
+		if len(msgs) == 0 {
+			return nil, fmt.Errorf("MCP tool %s reported an error", toolName)
+		}
+		return nil, fmt.Errorf("MCP tool %s reported an error: %s", toolName, strings.Join(msgs, "\n"))
+	}
 
 	// Return the content from the response
 	return resp.Content, nil
@@ -326,12 +975,17 @@ func (m *MCPManager) executeMCPTool(ctx context.Context, mcpClient *client.Clien
 // Close closes all MCP client connections
 func (m *MCPManager) Close() {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	clients := m.clients
+	m.clients = make(map[string]*MCPClientWrapper)
+	m.status = make(map[string]error)
+	m.mu.Unlock()
 
-	for _, clientWrapper := range m.clients {
+	// Lock order is clientWrapper.mu before m.mu (see reconnect), so close clients without holding m.mu.
+	for _, clientWrapper := range clients {
+		clientWrapper.mu.Lock()
 		if clientWrapper.client != nil {
 			clientWrapper.client.Close()
 		}
+		clientWrapper.mu.Unlock()
 	}
-	m.clients = make(map[string]*MCPClientWrapper)
 }
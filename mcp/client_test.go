@@ -0,0 +1,806 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"sketch.dev/llm"
+)
+
+// newTestMCPServer returns an MCP server exposing a single "echo" tool.
+func newTestMCPServer() *server.MCPServer {
+	s := server.NewMCPServer("test", "1.0.0")
+	s.AddTool(
+		mcp.NewTool("echo", mcp.WithDescription("Echo the message"), mcp.WithString("message", mcp.Required())),
+		func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			msg, err := req.RequireString("message")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText("echo: " + msg), nil
+		},
+	)
+	s.AddResource(
+		mcp.NewResource("test://greeting", "greeting", mcp.WithResourceDescription("A friendly greeting"), mcp.WithMIMEType("text/plain")),
+		func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{
+				mcp.TextResourceContents{URI: req.Params.URI, MIMEType: "text/plain", Text: "hello there"},
+			}, nil
+		},
+	)
+	s.AddPrompt(
+		mcp.NewPrompt("review", mcp.WithPromptDescription("Review some code"), mcp.WithArgument("lang", mcp.ArgumentDescription("Language"), mcp.RequiredArgument())),
+		func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return mcp.NewGetPromptResult("Code review", []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("Please review this "+req.Params.Arguments["lang"]+" code.")),
+				mcp.NewPromptMessage(mcp.RoleAssistant, mcp.NewTextContent("Sure.")),
+			}), nil
+		},
+	)
+	return s
+}
+
+// TestMain lets the test binary double as a stdio MCP server.
+func TestMain(m *testing.M) {
+	if os.Getenv("SKETCH_MCP_TEST_SLEEPER") != "" {
+		// A process started by a stdio server that ignores SIGTERM and never exits on its own.
+		signal.Ignore(syscall.SIGTERM)
+		select {}
+	}
+	if os.Getenv("SKETCH_MCP_TEST_STDIO_SERVER") != "" {
+		if marker := os.Getenv("SKETCH_MCP_TEST_HANG_ON_RESTART"); marker != "" {
+			if _, err := os.Stat(marker); err == nil {
+				select {} // started before: never answer
+			}
+			os.WriteFile(marker, nil, 0o644)
+		}
+		s := newTestMCPServer()
+		s.AddTool(mcp.NewTool("crash"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			os.Exit(1)
+			return nil, nil
+		})
+		s.AddTool(mcp.NewTool("cwd"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			wd, err := os.Getwd()
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(wd), nil
+		})
+		s.AddTool(mcp.NewTool("freeze"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			syscall.Kill(os.Getpid(), syscall.SIGSTOP)
+			return nil, nil
+		})
+		s.AddTool(mcp.NewTool("spawn"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			cmd := exec.Command(os.Args[0])
+			cmd.Env = append(os.Environ(), "SKETCH_MCP_TEST_SLEEPER=1")
+			if err := cmd.Start(); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return mcp.NewToolResultText(strconv.Itoa(cmd.Process.Pid)), nil
+		})
+		err := server.ServeStdio(s)
+		if os.Getenv("SKETCH_MCP_TEST_STDIO_SERVER") == "stubborn" {
+			select {} // refuse to exit
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestConnectToServerConfigsTransports(t *testing.T) {
+	tests := []struct {
+		name      string
+		newServer func(*server.MCPServer) *httptest.Server
+		config    func(url string) ServerConfig
+	}{
+		{
+			name: "sse",
+			newServer: func(s *server.MCPServer) *httptest.Server {
+				return server.NewTestServer(s)
+			},
+			config: func(url string) ServerConfig {
+				return ServerConfig{Name: "ssetest", Type: "sse", URL: url + "/sse"}
+			},
+		},
+		{
+			name: "http",
+			newServer: func(s *server.MCPServer) *httptest.Server {
+				return server.NewTestStreamableHTTPServer(s)
+			},
+			config: func(url string) ServerConfig {
+				return ServerConfig{Name: "httptest", Type: "http", URL: url + "/mcp"}
+			},
+		},
+		{
+			name: "stdio",
+			config: func(string) ServerConfig {
+				return ServerConfig{
+					Name:    "stdiotest",
+					Command: os.Args[0],
+					Env:     map[string]string{"SKETCH_MCP_TEST_STDIO_SERVER": "1"},
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var url string
+			if tt.newServer != nil {
+				ts := tt.newServer(newTestMCPServer())
+				defer ts.Close()
+				url = ts.URL
+			}
+
+			ctx := context.Background()
+			m := NewMCPManager()
+			defer m.Close()
+
+			config := tt.config(url)
+			connections, errs := m.ConnectToServerConfigs(ctx, []ServerConfig{config}, 5*time.Second, nil)
+			if len(errs) > 0 {
+				t.Fatalf("ConnectToServerConfigs errors: %v", errs)
+			}
+			if len(connections) != 1 {
+				t.Fatalf("got %d connections, want 1", len(connections))
+			}
+			conn := connections[0]
+			tool := findTool(conn.Tools, config.Name+"_echo")
+			if tool == nil {
+				t.Fatalf("echo tool not found in %v", conn.ToolNames)
+			}
+
+			input, _ := json.Marshal(map[string]string{"message": "hello"})
+			out, err := tool.Run(ctx, input)
+			if err != nil {
+				t.Fatalf("tool.Run: %v", err)
+			}
+			if len(out) != 1 || !strings.Contains(out[0].Text, "echo: hello") {
+				t.Errorf("unexpected tool output: %+v", out)
+			}
+		})
+	}
+}
+
+func TestConvertMCPContent(t *testing.T) {
+	got := convertMCPContent([]mcp.Content{
+		mcp.NewTextContent("hello"),
+		mcp.NewImageContent("iVBORw0KGgo=", "image/png"),
+		mcp.NewImageContent("R0lGODlh", "image/gif"),
+		mcp.NewEmbeddedResource(mcp.TextResourceContents{URI: "file:///a.txt", Text: "file contents"}),
+	})
+	if len(got) != 4 {
+		t.Fatalf("got %d contents, want 4: %+v", len(got), got)
+	}
+	if got[0].Text != "hello" {
+		t.Errorf("text content = %q, want %q", got[0].Text, "hello")
+	}
+	if got[1].MediaType != "image/png" || got[1].Data != "iVBORw0KGgo=" {
+		t.Errorf("png content = %+v, want image/png with data", got[1])
+	}
+	if got[2].MediaType != "" || !strings.Contains(got[2].Text, "image/gif") {
+		t.Errorf("gif content = %+v, want text description", got[2])
+	}
+	if got[3].Text != "file contents" {
+		t.Errorf("resource content = %q, want %q", got[3].Text, "file contents")
+	}
+}
+
+func TestParseServerConfigsToolTimeout(t *testing.T) {
+	configs, errs := ParseServerConfigs(context.Background(), []string{
+		`{"name": "a", "command": "a"}`,
+		`{"name": "b", "command": "b", "tool_timeout": "5m"}`,
+		`{"name": "c", "command": "c", "tool_timeout": "soon"}`,
+		`{"name": "d", "command": "d", "tool_timeout": "-1s"}`,
+	})
+	if len(errs) != 2 {
+		t.Errorf("got %d errors, want 2: %v", len(errs), errs)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("got %d configs, want 2", len(configs))
+	}
+	if got := configs[0].toolTimeout(); got != DefaultToolTimeout {
+		t.Errorf("default toolTimeout = %v, want %v", got, DefaultToolTimeout)
+	}
+	if got := configs[1].toolTimeout(); got != 5*time.Minute {
+		t.Errorf("toolTimeout = %v, want 5m", got)
+	}
+}
+
+// connectTest connects a new MCPManager to the server described by config,
+// closing the manager when the test ends.
+func connectTest(t *testing.T, config ServerConfig) (*MCPManager, MCPServerConnection) {
+	t.Helper()
+	m := NewMCPManager()
+	t.Cleanup(m.Close)
+	connections, errs := m.ConnectToServerConfigs(context.Background(), []ServerConfig{config}, 5*time.Second, nil)
+	if len(errs) > 0 || len(connections) != 1 {
+		t.Fatalf("ConnectToServerConfigs: %v", errs)
+	}
+	return m, connections[0]
+}
+
+func TestToolTimeout(t *testing.T) {
+	s := server.NewMCPServer("test", "1.0.0")
+	s.AddTool(mcp.NewTool("hang"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	ts := server.NewTestStreamableHTTPServer(s)
+	defer ts.Close()
+
+	_, conn := connectTest(t, ServerConfig{Name: "slow", Type: "http", URL: ts.URL + "/mcp", ToolTimeout: "100ms"})
+
+	start := time.Now()
+	_, err := conn.Tools[0].Run(context.Background(), nil)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("tool call took %v, want about 100ms", elapsed)
+	}
+}
+
+func findTool(tools []*llm.Tool, name string) *llm.Tool {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	return nil
+}
+
+func TestReconnectAfterServerExit(t *testing.T) {
+	ctx := context.Background()
+	_, conn := connectTest(t, ServerConfig{
+		Name:        "stdiotest",
+		Command:     os.Args[0],
+		Env:         map[string]string{"SKETCH_MCP_TEST_STDIO_SERVER": "1"},
+		ToolTimeout: "500ms",
+	})
+	tools := conn.Tools
+
+	// The server exits without responding, so this call times out.
+	if _, err := findTool(tools, "stdiotest_crash").Run(ctx, nil); err == nil {
+		t.Fatal("expected crash tool call to fail")
+	}
+
+	input, _ := json.Marshal(map[string]string{"message": "again"})
+	out, err := findTool(tools, "stdiotest_echo").Run(ctx, input)
+	if err != nil {
+		t.Fatalf("tool call after server exit: %v", err)
+	}
+	if len(out) != 1 || out[0].Text != "echo: again" {
+		t.Errorf("unexpected tool output: %+v", out)
+	}
+}
+
+func TestReconnectTimeout(t *testing.T) {
+	setShortTimeouts(t)
+	oldReconnect := reconnectTimeout
+	reconnectTimeout = 300 * time.Millisecond
+	t.Cleanup(func() { reconnectTimeout = oldReconnect })
+
+	ctx := context.Background()
+	m, conn := connectTest(t, ServerConfig{
+		Name:    "hangs",
+		Command: os.Args[0],
+		Env: map[string]string{
+			"SKETCH_MCP_TEST_STDIO_SERVER":    "1",
+			"SKETCH_MCP_TEST_HANG_ON_RESTART": filepath.Join(t.TempDir(), "started"),
+		},
+		ToolTimeout: "500ms",
+	})
+	if _, err := findTool(conn.Tools, "hangs_crash").Run(ctx, nil); err == nil {
+		t.Fatal("expected crash tool call to fail")
+	}
+
+	// The restarted server never finishes the handshake, so the reconnect times out.
+	errc := make(chan error, 1)
+	go func() {
+		input, _ := json.Marshal(map[string]string{"message": "again"})
+		_, err := findTool(conn.Tools, "hangs_echo").Run(ctx, input)
+		errc <- err
+	}()
+
+	// The reconnect must not block callers that only need the current client.
+	time.Sleep(100 * time.Millisecond)
+	got := make(chan struct{})
+	go func() {
+		m.currentClient("hangs")
+		close(got)
+	}()
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Error("currentClient blocked while reconnecting")
+	}
+
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("expected tool call to fail when the server cannot be reconnected")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconnect to a server that never answers did not time out")
+	}
+	if err := m.ConnectionStatus()["hangs"]; err == nil {
+		t.Error("ConnectionStatus does not report the failed reconnect")
+	}
+}
+
+func TestResources(t *testing.T) {
+	ts := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer ts.Close()
+
+	ctx := context.Background()
+	m, conn := connectTest(t, ServerConfig{Name: "res", Type: "http", URL: ts.URL + "/mcp"})
+
+	resources := m.GetAllResources()
+	want := []MCPResource{{
+		ServerName:  "res",
+		URI:         "test://greeting",
+		Name:        "greeting",
+		Description: "A friendly greeting",
+		MIMEType:    "text/plain",
+	}}
+	if !slices.Equal(resources, want) {
+		t.Errorf("GetAllResources() = %+v, want %+v", resources, want)
+	}
+
+	out, err := m.ReadResource(ctx, "res", "test://greeting")
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	if len(out) != 1 || out[0].Text != "hello there" {
+		t.Errorf("ReadResource() = %+v, want hello there", out)
+	}
+
+	tool := findTool(conn.Tools, "res_read_resource")
+	if tool == nil {
+		t.Fatal("read_resource tool not registered")
+	}
+	if !strings.Contains(tool.Description, "test://greeting") {
+		t.Errorf("read_resource description does not list resources: %q", tool.Description)
+	}
+	out, err = tool.Run(ctx, json.RawMessage(`{"uri": "test://greeting"}`))
+	if err != nil {
+		t.Fatalf("read_resource tool: %v", err)
+	}
+	if len(out) != 1 || out[0].Text != "hello there" {
+		t.Errorf("read_resource tool = %+v, want hello there", out)
+	}
+}
+
+func TestReadResourceToolListsLimitedResources(t *testing.T) {
+	var resources []mcp.Resource
+	for i := range maxListedResources + 10 {
+		resources = append(resources, mcp.NewResource(fmt.Sprintf("test://r%d", i), fmt.Sprintf("r%d", i)))
+	}
+	desc := NewMCPManager().readResourceTool(ServerConfig{Name: "many"}, resources).Description
+	last := fmt.Sprintf("test://r%d:", maxListedResources-1)
+	if !strings.Contains(desc, last) || strings.Contains(desc, fmt.Sprintf("test://r%d:", maxListedResources)) {
+		t.Errorf("description does not list exactly the first %d resources:\n%s", maxListedResources, desc)
+	}
+	if !strings.Contains(desc, "...and 10 more") {
+		t.Errorf("description does not count the unlisted resources:\n%s", desc)
+	}
+}
+
+func TestPrompts(t *testing.T) {
+	ts := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer ts.Close()
+
+	ctx := context.Background()
+	m, _ := connectTest(t, ServerConfig{Name: "prompts", Type: "http", URL: ts.URL + "/mcp"})
+
+	prompts := m.GetAllPrompts()
+	if len(prompts) != 1 {
+		t.Fatalf("GetAllPrompts() returned %d prompts, want 1", len(prompts))
+	}
+	p := prompts[0]
+	if p.ServerName != "prompts" || p.Name != "review" || p.Description != "Review some code" {
+		t.Errorf("unexpected prompt: %+v", p)
+	}
+	wantArgs := []MCPPromptArgument{{Name: "lang", Description: "Language", Required: true}}
+	if !slices.Equal(p.Arguments, wantArgs) {
+		t.Errorf("prompt arguments = %+v, want %+v", p.Arguments, wantArgs)
+	}
+
+	messages, err := m.GetPrompt(ctx, "prompts", "review", map[string]string{"lang": "Go"})
+	if err != nil {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("GetPrompt returned %d messages, want 2", len(messages))
+	}
+	if messages[0].Role != llm.MessageRoleUser || messages[0].Content[0].Text != "Please review this Go code." {
+		t.Errorf("first message = %+v", messages[0])
+	}
+	if messages[1].Role != llm.MessageRoleAssistant || messages[1].Content[0].Text != "Sure." {
+		t.Errorf("second message = %+v", messages[1])
+	}
+}
+
+func TestServerConfigIncludeTool(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ServerConfig
+		tool   string
+		want   bool
+	}{
+		{"no filters", ServerConfig{}, "anything", true},
+		{"allowed exactly", ServerConfig{Tools: []string{"read_file"}}, "read_file", true},
+		{"not allowed", ServerConfig{Tools: []string{"read_file"}}, "write_file", false},
+		{"allowed by glob", ServerConfig{Tools: []string{"read_*"}}, "read_dir", true},
+		{"excluded", ServerConfig{ExcludeTools: []string{"delete_*"}}, "delete_file", false},
+		{"not excluded", ServerConfig{ExcludeTools: []string{"delete_*"}}, "read_file", true},
+		{"exclude wins", ServerConfig{Tools: []string{"*_file"}, ExcludeTools: []string{"delete_file"}}, "delete_file", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.includeTool(tt.tool); got != tt.want {
+				t.Errorf("includeTool(%q) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolFilters(t *testing.T) {
+	s := server.NewMCPServer("test", "1.0.0")
+	for _, name := range []string{"read_file", "write_file", "delete_file"} {
+		s.AddTool(mcp.NewTool(name), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(name), nil
+		})
+	}
+	// Resources add a read_resource tool, which the filters apply to as well.
+	s.AddResource(mcp.NewResource("test://notes", "notes"), func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return nil, nil
+	})
+	ts := server.NewTestStreamableHTTPServer(s)
+	defer ts.Close()
+
+	ctx := context.Background()
+	configs, errs := ParseServerConfigs(ctx, []string{
+		`{"name": "files", "type": "http", "url": "` + ts.URL + `/mcp", "tools": ["*_file"], "exclude_tools": ["delete_*"]}`,
+		`{"name": "readers", "type": "http", "url": "` + ts.URL + `/mcp", "tools": ["read_*"]}`,
+		`{"name": "bad", "command": "x", "tools": ["[oops"]}`,
+	})
+	if len(errs) != 1 {
+		t.Errorf("got %d parse errors, want 1 for the bad pattern: %v", len(errs), errs)
+	}
+
+	m := NewMCPManager()
+	defer m.Close()
+	connections, errs := m.ConnectToServerConfigs(ctx, configs, 5*time.Second, nil)
+	if len(errs) > 0 || len(connections) != 2 {
+		t.Fatalf("ConnectToServerConfigs: %v", errs)
+	}
+	wants := [][]string{
+		{"files_read_file", "files_write_file"},
+		{"readers_read_file", "readers_read_resource"},
+	}
+	for i, want := range wants {
+		var names []string
+		for _, tool := range connections[i].Tools {
+			names = append(names, tool.Name)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, want) {
+			t.Errorf("%s tools = %v, want %v", connections[i].ServerName, names, want)
+		}
+	}
+}
+
+func TestNameCollisions(t *testing.T) {
+	newServer := func(tools ...string) *httptest.Server {
+		s := server.NewMCPServer("test", "1.0.0")
+		for _, name := range tools {
+			s.AddTool(mcp.NewTool(name), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return mcp.NewToolResultText(name), nil
+			})
+		}
+		return server.NewTestStreamableHTTPServer(s)
+	}
+	first := newServer("ping")
+	defer first.Close()
+	second := newServer("ping")
+	defer second.Close()
+	third := newServer("b_c")
+	defer third.Close()
+	fourth := newServer("c")
+	defer fourth.Close()
+
+	ctx := context.Background()
+	m := NewMCPManager()
+	defer m.Close()
+	connections, errs := m.ConnectToServerConfigs(ctx, []ServerConfig{
+		{Name: "dup", Type: "http", URL: first.URL + "/mcp"},
+		{Name: "dup", Type: "http", URL: second.URL + "/mcp"},
+		{Name: "a", Type: "http", URL: third.URL + "/mcp"},
+		{Name: "a_b", Type: "http", URL: fourth.URL + "/mcp"},
+	}, 5*time.Second, nil)
+	if len(errs) > 0 {
+		t.Fatalf("ConnectToServerConfigs: %v", errs)
+	}
+
+	var servers, tools []string
+	for _, conn := range connections {
+		servers = append(servers, conn.ServerName)
+		for _, tool := range conn.Tools {
+			tools = append(tools, tool.Name)
+		}
+	}
+	if want := []string{"dup", "dup_2", "a", "a_b"}; !slices.Equal(servers, want) {
+		t.Errorf("servers = %v, want %v", servers, want)
+	}
+	if want := []string{"dup_ping", "dup_2_ping", "a_b_c", "a_b_c_2"}; !slices.Equal(tools, want) {
+		t.Errorf("tools = %v, want %v", tools, want)
+	}
+	if got := connections[1].ToolNames; !slices.Equal(got, []string{"ping"}) {
+		t.Errorf("ToolNames for dup_2 = %v, want [ping]", got)
+	}
+
+	// The renamed tool still calls the right server tool.
+	out, err := connections[3].Tools[0].Run(ctx, nil)
+	if err != nil {
+		t.Fatalf("renamed tool: %v", err)
+	}
+	if len(out) != 1 || out[0].Text != "c" {
+		t.Errorf("renamed tool output = %+v, want c", out)
+	}
+}
+
+func TestToolReportedError(t *testing.T) {
+	ts := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer ts.Close()
+
+	_, conn := connectTest(t, ServerConfig{Name: "errs", Type: "http", URL: ts.URL + "/mcp"})
+
+	// The echo tool reports a missing argument with IsError set.
+	_, err := findTool(conn.Tools, "errs_echo").Run(context.Background(), json.RawMessage(`{}`))
+	if err == nil {
+		t.Fatal("expected an error for a tool result with IsError set")
+	}
+	if !strings.Contains(err.Error(), "message") {
+		t.Errorf("error %q does not include the tool's error text", err)
+	}
+}
+
+func TestConnectionStatus(t *testing.T) {
+	ts := server.NewTestStreamableHTTPServer(newTestMCPServer())
+	defer ts.Close()
+
+	ctx := context.Background()
+	m := NewMCPManager()
+	defer m.Close()
+	_, errs := m.ConnectToServerConfigs(ctx, []ServerConfig{
+		{Name: "good", Type: "http", URL: ts.URL + "/mcp"},
+		{Name: "bad", Type: "http"},
+	}, 5*time.Second, nil)
+	if len(errs) != 1 {
+		t.Errorf("got %d errors, want 1: %v", len(errs), errs)
+	}
+
+	status := m.ConnectionStatus()
+	if len(status) != 2 {
+		t.Fatalf("ConnectionStatus() = %v, want 2 entries", status)
+	}
+	if err, ok := status["good"]; !ok || err != nil {
+		t.Errorf("status[good] = %v, %v; want nil, true", err, ok)
+	}
+	if err := status["bad"]; err == nil || !strings.Contains(err.Error(), "URL is required") {
+		t.Errorf("status[bad] = %v, want URL required error", err)
+	}
+}
+
+func TestStdioWorkingDirectory(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	m := NewMCPManager()
+	defer m.Close()
+	connections, errs := m.ConnectToServerConfigs(ctx, []ServerConfig{
+		{
+			Name:    "cwd",
+			Command: os.Args[0],
+			Env:     map[string]string{"SKETCH_MCP_TEST_STDIO_SERVER": "1"},
+			Dir:     dir,
+		},
+		{
+			Name:    "missing",
+			Command: os.Args[0],
+			Env:     map[string]string{"SKETCH_MCP_TEST_STDIO_SERVER": "1"},
+			Dir:     filepath.Join(dir, "does-not-exist"),
+		},
+	}, 5*time.Second, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "working directory") {
+		t.Errorf("errors = %v, want one invalid working directory error", errs)
+	}
+	if len(connections) != 1 {
+		t.Fatalf("got %d connections, want 1", len(connections))
+	}
+
+	out, err := findTool(connections[0].Tools, "cwd_cwd").Run(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0].Text != dir {
+		t.Errorf("server working directory = %+v, want %s", out, dir)
+	}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	s := server.NewMCPServer("test", "1.0.0")
+	s.AddTool(mcp.NewTool("slow"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return mcp.NewToolResultText("done"), nil
+	})
+	ts := server.NewTestStreamableHTTPServer(s)
+	defer ts.Close()
+
+	ctx := context.Background()
+	_, conn := connectTest(t, ServerConfig{Name: "limited", Type: "http", URL: ts.URL + "/mcp", MaxConcurrency: 2})
+	tool := conn.Tools[0]
+
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tool.Run(ctx, nil); err != nil {
+				t.Errorf("tool call: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got != 2 {
+		t.Errorf("max concurrent calls = %d, want 2", got)
+	}
+}
+
+// stdioProcess returns the server process for a connected stdio server.
+func stdioProcess(t *testing.T, m *MCPManager, serverName string) *stdioTransport {
+	t.Helper()
+	mcpClient, err := m.currentClient(serverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, ok := mcpClient.GetTransport().(*stdioTransport)
+	if !ok {
+		t.Fatalf("server %s does not use a stdio transport", serverName)
+	}
+	return st
+}
+
+func setShortTimeouts(t *testing.T) {
+	oldGrace, oldPing := stdioCloseGrace, pingTimeout
+	stdioCloseGrace, pingTimeout = 100*time.Millisecond, 200*time.Millisecond
+	t.Cleanup(func() { stdioCloseGrace, pingTimeout = oldGrace, oldPing })
+}
+
+func TestCloseKillsStubbornStdioServer(t *testing.T) {
+	setShortTimeouts(t)
+	m, _ := connectTest(t, ServerConfig{
+		Name:    "stubborn",
+		Command: os.Args[0],
+		Env:     map[string]string{"SKETCH_MCP_TEST_STDIO_SERVER": "stubborn"},
+	})
+	st := stdioProcess(t, m, "stubborn")
+
+	m.Close()
+	select {
+	case <-st.exited:
+	default:
+		t.Fatal("Close returned before the server process exited")
+	}
+	if ws, ok := st.cmd.ProcessState.Sys().(syscall.WaitStatus); !ok || ws.Signal() != syscall.SIGKILL {
+		t.Errorf("server exited with %v, want SIGKILL", st.cmd.ProcessState)
+	}
+}
+
+// processExited reports whether the process pid has exited.
+// An exited process that has not been reaped yet counts as exited.
+func processExited(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// The state follows the parenthesized command name.
+	_, state, _ := strings.Cut(string(stat), ") ")
+	return strings.HasPrefix(state, "Z")
+}
+
+func TestCloseKillsStdioServerChildren(t *testing.T) {
+	setShortTimeouts(t)
+	m, conn := connectTest(t, ServerConfig{
+		Name:    "parent",
+		Command: os.Args[0],
+		Env:     map[string]string{"SKETCH_MCP_TEST_STDIO_SERVER": "stubborn"},
+	})
+	out, err := findTool(conn.Tools, "parent_spawn").Run(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(out[0].Text)
+	if err != nil {
+		t.Fatalf("spawn tool returned %+v", out)
+	}
+	t.Cleanup(func() { syscall.Kill(pid, syscall.SIGKILL) })
+
+	m.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for !processExited(pid) {
+		if time.Now().After(deadline) {
+			t.Fatal("process started by the server is still running after Close")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReapUnresponsiveStdioServer(t *testing.T) {
+	setShortTimeouts(t)
+	ctx := context.Background()
+	m, conn := connectTest(t, ServerConfig{
+		Name:        "frozen",
+		Command:     os.Args[0],
+		Env:         map[string]string{"SKETCH_MCP_TEST_STDIO_SERVER": "1"},
+		ToolTimeout: "200ms",
+	})
+	st := stdioProcess(t, m, "frozen")
+
+	// The server stops itself, so the call times out and the ping after it fails.
+	if _, err := findTool(conn.Tools, "frozen_freeze").Run(ctx, nil); err == nil {
+		t.Fatal("expected freeze tool call to time out")
+	}
+	select {
+	case <-st.exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("unresponsive server process was not terminated")
+	}
+
+	input, _ := json.Marshal(map[string]string{"message": "back"})
+	out, err := findTool(conn.Tools, "frozen_echo").Run(ctx, input)
+	if err != nil {
+		t.Fatalf("tool call after reaping server: %v", err)
+	}
+	if len(out) != 1 || out[0].Text != "echo: back" {
+		t.Errorf("unexpected tool output: %+v", out)
+	}
+}
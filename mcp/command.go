@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// stdioCommand returns the program and arguments to run for a stdio server.
+// When no args are configured, command may be a full command line such as
+// `mytool --config "/path with spaces/cfg.json"`, which is split into words
+// following shell quoting rules. A command naming an existing file is used
+// as-is, so paths containing spaces keep working without quotes.
+func stdioCommand(config ServerConfig) (string, []string, error) {
+	if len(config.Args) > 0 || !strings.ContainsAny(config.Command, " \t\n'\"\\") {
+		return config.Command, config.Args, nil
+	}
+	if _, err := os.Stat(config.Command); err == nil {
+		return config.Command, nil, nil
+	}
+	words, err := splitCommand(config.Command)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid command %q: %w", config.Command, err)
+	}
+	if len(words) == 0 {
+		return "", nil, fmt.Errorf("command is required for stdio transport")
+	}
+	return words[0], words[1:], nil
+}
+
+// splitCommand splits s into words the way a POSIX shell would, honoring
+// single quotes, double quotes and backslash escapes. It does not expand
+// variables, globs or any other shell syntax.
+func splitCommand(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false // distinguishes an empty quoted word from no word
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 == len(s) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			if s[i] != '\n' { // backslash-newline is a line continuation
+				word.WriteByte(s[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				// Within double quotes, backslash only escapes characters that are otherwise special.
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if i == len(s) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// stdioCloseGrace is how long Close waits for a stdio server to exit after
+// closing its stdin, and again after SIGTERM, before killing it.
+var stdioCloseGrace = 2 * time.Second
+
+// stdioTransport is a stdio transport for a server subprocess that we start ourselves,
+// which lets us control its working directory and make sure it exits on Close.
+type stdioTransport struct {
+	*transport.Stdio
+	cmd    *exec.Cmd
+	stdout *os.File      // our end of the server's stdout, which transport.Stdio does not close
+	exited chan struct{} // closed when cmd has exited
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// startStdio starts the server process for a stdio config.
+func startStdio(config ServerConfig) (*stdioTransport, error) {
+	command, args, err := stdioCommand(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Dir != "" {
+		fi, err := os.Stat(config.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid working directory: %w", err)
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("invalid working directory: %s is not a directory", config.Dir)
+		}
+	}
+
+	cmd := exec.Command(command, args...)
+	cmd.Dir = config.Dir
+	// Run the server in its own process group, so that Close can also stop any processes it starts,
+	// such as the real server behind an npx or uvx launcher.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Env = os.Environ()
+	for k, v := range config.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	// Create the pipes ourselves rather than with cmd.StdinPipe and friends:
+	// cmd.Wait closes those, even while the transport is still reading from them.
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		closeFiles(stdinR, stdinW)
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	// TODO: pipe stderr from the subprocess to our stderr, with each line prefixed with the server name.
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		closeFiles(stdinR, stdinW, stdoutR, stdoutW)
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdinR, stdoutW, stderrW
+	err = cmd.Start()
+	// The child has its own copies of its ends of the pipes.
+	closeFiles(stdinR, stdoutW, stderrW)
+	if err != nil {
+		closeFiles(stdinW, stdoutR, stderrR)
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+	t := &stdioTransport{
+		Stdio:  transport.NewIO(closedAsEOF{stdoutR}, stdinW, stderrR),
+		cmd:    cmd,
+		stdout: stdoutR,
+		exited: make(chan struct{}),
+	}
+	go func() {
+		cmd.Wait()
+		close(t.exited)
+	}()
+	return t, nil
+}
+
+// Close closes the server's stdin, which asks it to exit.
+// A server that has not exited after stdioCloseGrace gets SIGTERM,
+// and then SIGKILL after another stdioCloseGrace, so Close never leaks a process.
+// Signals go to the server's whole process group.
+func (t *stdioTransport) Close() error {
+	t.closeOnce.Do(func() {
+		defer t.stdout.Close()
+		t.closeErr = t.Stdio.Close()
+		for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGKILL} {
+			select {
+			case <-t.exited:
+				return
+			case <-time.After(stdioCloseGrace):
+			}
+			slog.Warn("MCP stdio server did not exit; signaling", "pid", t.cmd.Process.Pid, "signal", sig)
+			syscall.Kill(-t.cmd.Process.Pid, sig)
+		}
+		<-t.exited
+	})
+	return t.closeErr
+}
+
+// closedAsEOF reports reads from a closed file as io.EOF.
+// transport.Stdio prints any read error other than io.EOF,
+// and Close closes stdout while its reader may still be running.
+type closedAsEOF struct{ f *os.File }
+
+func (r closedAsEOF) Read(p []byte) (int, error) {
+	n, err := r.f.Read(p)
+	if errors.Is(err, os.ErrClosed) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func closeFiles(files ...*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"server", []string{"server"}},
+		{"  server   --flag  ", []string{"server", "--flag"}},
+		{`mytool --config "/path with spaces/cfg.json"`, []string{"mytool", "--config", "/path with spaces/cfg.json"}},
+		{`node 'my server.js'`, []string{"node", "my server.js"}},
+		{`echo '{"a": 1}'`, []string{"echo", `{"a": 1}`}},
+		{`echo "{\"a\": 1}"`, []string{"echo", `{"a": 1}`}},
+		{`echo "a\b"`, []string{"echo", `a\b`}},
+		{`echo path\ with\ spaces`, []string{"echo", "path with spaces"}},
+		{`echo "" ''`, []string{"echo", "", ""}},
+		{`echo a"b c"d`, []string{"echo", "ab cd"}},
+		{`echo $HOME`, []string{"echo", "$HOME"}},
+		{"echo a\\\nb", []string{"echo", "ab"}},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.in)
+		if err != nil {
+			t.Errorf("splitCommand(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitCommandErrors(t *testing.T) {
+	for _, in := range []string{`echo "unterminated`, `echo 'unterminated`, `echo trailing\`} {
+		if got, err := splitCommand(in); err == nil {
+			t.Errorf("splitCommand(%q) = %q, want error", in, got)
+		}
+	}
+}
+
+func TestStdioCommand(t *testing.T) {
+	dir := t.TempDir()
+	spacedPath := filepath.Join(dir, "my server")
+	if err := os.WriteFile(spacedPath, nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		config   ServerConfig
+		wantCmd  string
+		wantArgs []string
+	}{
+		{"plain", ServerConfig{Command: "server"}, "server", nil},
+		{"explicit args", ServerConfig{Command: "my tool", Args: []string{"a b"}}, "my tool", []string{"a b"}},
+		{"command line", ServerConfig{Command: `mytool --config "/x y/cfg.json"`}, "mytool", []string{"--config", "/x y/cfg.json"}},
+		{"existing path with spaces", ServerConfig{Command: spacedPath}, spacedPath, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, args, err := stdioCommand(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cmd != tt.wantCmd || !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("stdioCommand() = %q, %q; want %q, %q", cmd, args, tt.wantCmd, tt.wantArgs)
+			}
+		})
+	}
+}
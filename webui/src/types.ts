@@ -163,6 +163,7 @@ export interface DiffFile {
 	status: string;
 	additions: number;
 	deletions: number;
+	binary: boolean;
 }
 
 export interface GitLogEntry {
@@ -54,6 +54,7 @@ export class MockGitDataService implements GitDataService {
       new_hash: "def0123456789abcdef0123456789abcdef0123",
       additions: 54,
       deletions: 0,
+      binary: false,
     },
     {
       path: "src/components/RangePicker.js",
@@ -65,6 +66,7 @@ export class MockGitDataService implements GitDataService {
       new_hash: "cde0123456789abcdef0123456789abcdef0123",
       additions: 32,
       deletions: 0,
+      binary: false,
     },
     {
       path: "src/components/App.js",
@@ -76,6 +78,7 @@ export class MockGitDataService implements GitDataService {
       new_hash: "bcd0123456789abcdef0123456789abcdef0123",
       additions: 15,
       deletions: 3,
+      binary: false,
     },
     {
       path: "src/components/DialogPicker.js",
@@ -87,6 +90,7 @@ export class MockGitDataService implements GitDataService {
       new_hash: "hij0123456789abcdef0123456789abcdef0123",
       additions: 8,
       deletions: 2,
+      binary: false,
     },
     {
       path: "src/components/RangeSelector.js",
@@ -98,6 +102,7 @@ export class MockGitDataService implements GitDataService {
       new_hash: "klm0123456789abcdef0123456789abcdef0123",
       additions: 5,
       deletions: 3,
+      binary: false,
     },
     {
       path: "src/styles/main.css",
@@ -109,6 +114,7 @@ export class MockGitDataService implements GitDataService {
       new_hash: "ghi0123456789abcdef0123456789abcdef0123",
       additions: 25,
       deletions: 8,
+      binary: false,
     },
   ];
 